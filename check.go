@@ -0,0 +1,172 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// CheckStatus is what [Checker.Check] found for a [PackageRef].
+type CheckStatus uint8
+
+const (
+	CheckOk           CheckStatus = iota
+	CheckMissing                  // no such package for the arc
+	CheckVirtual                  // only provided by other packages
+	CheckPinMissing               // the package exists but not the version
+	CheckTransitional             // exists but only to pull in another
+	CheckOtherArc                 // qualified for an arc that wasn't read
+)
+
+var checkStatusNames = [...]string{"ok", "missing", "virtual",
+	"pin-missing", "transitional", "other-arc"}
+
+func (me CheckStatus) String() string {
+	if int(me) < len(checkStatusNames) {
+		return checkStatusNames[me]
+	}
+	return "BUG: invalid CheckStatus"
+}
+
+// IsProblem returns true if the package as given isn't a real package
+// that can be installed (transitional packages and other arcs' packages
+// can be).
+func (me CheckStatus) IsProblem() bool {
+	return me == CheckMissing || me == CheckVirtual || me == CheckPinMissing
+}
+
+// CheckResult is a [PackageRef] with what was found: the deb (if it
+// exists) and the packages that might be used instead, the most likely
+// first.
+type CheckResult struct {
+	Ref          PackageRef
+	Status       CheckStatus
+	Deb          *Deb
+	Replacements []*Deb
+}
+
+// Checker checks package refs against a model's packages for an arc.
+type Checker struct {
+	model     *Model
+	arc       string
+	providers map[string][]*Deb // virtual package name to its providers
+	replacers map[string][]*Deb // package name to those that Replace it
+}
+
+// NewChecker returns a checker for the model's packages which are for the
+// given arc, e.g., "amd64".
+func NewChecker(model *Model, arc string) *Checker {
+	providers := map[string][]*Deb{}
+	replacers := map[string][]*Deb{}
+	for name := range model.Debs {
+		for _, deb := range model.VersionsFor(name, arc) {
+			for _, provided := range deb.ProvidedNames() {
+				providers[provided] = append(providers[provided], deb)
+			}
+			for _, group := range ParseRelations(deb.Replaces) {
+				for _, relation := range group {
+					replacers[relation.Name] = append(
+						replacers[relation.Name], deb)
+				}
+			}
+		}
+	}
+	for _, index := range []map[string][]*Deb{providers, replacers} {
+		for name, debs := range index {
+			slices.SortStableFunc(debs, func(a, b *Deb) int {
+				return cmp.Compare(a.Name, b.Name)
+			})
+			index[name] = slices.CompactFunc(debs, func(a, b *Deb) bool {
+				return a.Name == b.Name // keep the highest version
+			})
+		}
+	}
+	return &Checker{model: model, arc: arc, providers: providers,
+		replacers: replacers}
+}
+
+// Check returns whether the ref's package (and pinned version, if any) is
+// available for the arc from any of the sources read; for a virtual
+// package the replacements are its providers, for a missing package they
+// are any that replace it (or its 64-bit time_t successor, e.g.,
+// libfoo1t64), and for a transitional package they are what it depends
+// on. The result's Deb is the pinned version or else the highest.
+func (me *Checker) Check(ref PackageRef) CheckResult {
+	result := CheckResult{Ref: ref}
+	if ref.Arch != "" && !slices.Contains([]string{me.arc, "all", "any",
+		"native"}, ref.Arch) {
+		result.Status = CheckOtherArc
+		return result
+	}
+	debs := me.model.VersionsFor(ref.Name, me.arc)
+	if len(debs) == 0 {
+		if providers := me.providers[ref.Name]; len(providers) > 0 {
+			result.Status = CheckVirtual
+			result.Replacements = providers
+		} else {
+			result.Status = CheckMissing
+			result.Replacements = me.successors(ref.Name)
+		}
+		return result
+	}
+	result.Deb = debs[0]
+	if ref.Version != "" {
+		index := slices.IndexFunc(debs, func(deb *Deb) bool {
+			return versionMatches(deb.Version, ref.Version)
+		})
+		if index == -1 {
+			result.Status = CheckPinMissing
+			return result
+		}
+		result.Deb = debs[index]
+	}
+	if result.Deb.Kind() == KindTransitional {
+		result.Status = CheckTransitional
+		result.Replacements = me.dependedOn(result.Deb)
+	}
+	return result
+}
+
+// debFor returns the highest version of the named package for the arc or
+// nil.
+func (me *Checker) debFor(name string) *Deb {
+	if debs := me.model.VersionsFor(name, me.arc); len(debs) > 0 {
+		return debs[0]
+	}
+	return nil
+}
+
+// successors returns the packages that may have replaced the named one.
+func (me *Checker) successors(name string) []*Deb {
+	debs := slices.Clone(me.replacers[name])
+	if deb := me.debFor(name + "t64"); deb != nil &&
+		!slices.Contains(debs, deb) {
+		debs = slices.Insert(debs, 0, deb)
+	}
+	return debs
+}
+
+// dependedOn returns the (first alternative) packages that the
+// transitional deb depends on.
+func (me *Checker) dependedOn(deb *Deb) []*Deb {
+	var debs []*Deb
+	for _, group := range deb.Relations(DependsKind) {
+		if target := me.debFor(group[0].Name); target != nil &&
+			target != deb && !slices.Contains(debs, target) {
+			debs = append(debs, target)
+		}
+	}
+	return debs
+}
+
+// versionMatches returns true if the version is the pinned one; a pin
+// ending with "*" matches as a prefix.
+func versionMatches(version, pin string) bool {
+	if prefix, ok := strings.CutSuffix(pin, "*"); ok {
+		return strings.HasPrefix(version, prefix)
+	}
+	return CompareVersions(version, pin) == 0
+}
@@ -0,0 +1,76 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	model := modelOf(t, `Package: curl
+Version: 7.88.1-10
+
+Package: curl
+Version: 7.88.1-10+deb12u5
+
+Package: mawk
+Provides: awk
+
+Package: gawk
+Provides: awk
+
+Package: libssl3t64
+Replaces: libssl3
+
+Package: exim4
+Depends: exim4-daemon-light | exim4-daemon-heavy, exim4-base
+Description: metapackage to ease Exim MTA (v4) installation
+Section: metapackages
+
+Package: exim4-daemon-light
+
+Package: exim4-base
+
+Package: tzdata
+Architecture: all
+
+Package: libfoo1
+Architecture: i386`)
+	checker := NewChecker(&model, "amd64")
+	for _, test := range []struct {
+		ref  string
+		want string // STATUS DEB-VERSION REPLACEMENT...
+	}{
+		{"curl", "ok 7.88.1-10+deb12u5"},
+		{"curl=7.88.1-10", "ok 7.88.1-10"},
+		{"curl=7.88.1-10+*", "ok 7.88.1-10+deb12u5"},
+		{"curl=7.74.0-1.3", "pin-missing 7.88.1-10+deb12u5"},
+		{"awk", "virtual gawk mawk"},
+		{"libssl3", "missing libssl3t64"},
+		{"nosuch", "missing"},
+		{"exim4", "transitional 1.0-1 exim4-daemon-light exim4-base"},
+		{"tzdata", "ok 1.0-1"},
+		{"tzdata:all", "ok 1.0-1"},
+		{"libfoo1", "missing"}, // only for i386
+		{"libfoo1:i386", "other-arc"},
+	} {
+		ref, ok := newPackageRef(test.ref, 1)
+		if !ok {
+			t.Fatalf("invalid ref %q", test.ref)
+		}
+		result := checker.Check(ref)
+		got := []string{result.Status.String()}
+		if result.Deb != nil {
+			got = append(got, result.Deb.Version)
+		}
+		for _, deb := range result.Replacements {
+			got = append(got, deb.Name)
+		}
+		if gotText := strings.Join(got, " "); gotText != test.want {
+			t.Errorf("%s: expected %q, got %q", test.ref, test.want,
+				gotText)
+		}
+	}
+}
@@ -25,6 +25,7 @@ func main() {
 			}
 		}()
 	}
+	fltk.Lock() // packages are loaded in a goroutine; see App.loadPackages
 	fltk.SetScheme("Oxy")
 	fltk.SetScreenScale(0, config.Scale)
 	app := newApp(config)
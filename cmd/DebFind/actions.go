@@ -60,38 +60,61 @@ func (me *App) onFind() {
 
 func (me *App) makeQuery() *ds.Query {
 	query := ds.NewQuery()
-	sections := selected(me.sectionsBrowser)
-	query.Sections.Add(sections...)
-	if me.incNonFreeCheckbox.Value() {
-		for _, section := range sections {
-			if !strings.Contains(section, "/") {
-				nonfreeSection := nonfreePrefix + section
-				if _, ok := me.model.SectionsAndCounts[nonfreeSection]; ok {
-					query.Sections.Add(nonfreeSection) // add if it exists
-				}
-			}
-		}
-	}
+	query.Components.Unite(me.components)
+	query.NotKinds.Unite(me.hiddenKinds)
+	query.AddSections(selected(me.sectionsBrowser)...)
 	query.Tags.Add(selected(me.tagsBrowser)...)
 	query.TagsAnd = me.tagsMatchAllRadioButton.Value()
 	for _, word := range strings.Fields(me.wordsInput.Value()) {
 		query.Words.Add(strings.ToLower(word))
 	}
 	query.WordsAnd = me.wordsMatchAllRadioButton.Value()
+	query.SortBy, _ = ds.ParseSortKey(me.config.SortBy) // default: name
+	query.Reverse = me.config.SortReverse
 	return query
 }
 
+// onSort re-sorts the packages found by the given key, reversing the
+// order if they are already sorted by it.
+func (me *App) onSort(sortBy ds.SortKey) {
+	if me.config.SortBy == sortBy.String() {
+		me.config.SortReverse = !me.config.SortReverse
+	} else {
+		me.config.SortBy = sortBy.String()
+		me.config.SortReverse = false
+	}
+	me.updatePackagesHeader()
+	if me.lastQuery != nil {
+		me.lastQuery.SortBy = sortBy
+		me.lastQuery.Reverse = me.config.SortReverse
+		me.packagesBrowser.Clear()
+		me.updateResults(me.lastQuery)
+	}
+}
+
 func (me *App) updateResults(query *ds.Query) {
-	debs := query.SelectFrom(me.model)
-	me.updatePackagesLabel(len(debs))
+	me.lastQuery = query
+	result := query.Search(me.model)
+	me.populateSections(result.SectionsAndCounts)
+	me.populateTags(result.TagsAndCounts)
+	debs := result.Debs
+	me.updatePackagesLabel(result.Total)
 	if len(debs) == 0 {
 		me.onWarn("No matching packages found.")
 	} else {
 		me.updatePackageBrowserWidths()
 		bg := light1
 		for _, deb := range debs {
-			me.packagesBrowser.Add(fmt.Sprintf("@B%d@.%s\t@B%d@.%s", bg,
-				deb.Name, bg, deb.ShortDesc))
+			me.packagesBrowser.AddWithData(fmt.Sprintf(
+				"@B%d@.%s\t@B%d@.%s\t@B%d@.%s\t@B%d@r@.%s\t@B%d@.%s", bg,
+				deb.Name, bg, deb.QualifiedSection(), bg, deb.Version, bg,
+				ds.HumanSize(deb.Size), bg, deb.ShortDesc), deb.Name)
+			if snippets := result.Snippets[deb.Name]; len(snippets) > 0 {
+				// a second line (without tabs so it spans every column)
+				me.packagesBrowser.AddWithData(fmt.Sprintf(
+					"@B%d@i@C%d@.    %s", bg, snippetGray,
+					snippets[0].Text), deb.Name)
+			}
 			if bg == light1 {
 				bg = light2
 			} else {
@@ -104,34 +127,41 @@ func (me *App) updateResults(query *ds.Query) {
 	}
 }
 
+// updatePackageBrowserWidths sets the browser's column widths to those
+// of the header's buttons.
 func (me *App) updatePackageBrowserWidths() {
-	width := me.packagesBrowser.W()
-	left := min(200, width/2)
-	me.packagesBrowser.SetColumnWidths(left, width-left)
+	me.packagesHeader.Layout()
+	widths := make([]int, 0, len(me.headerButtons))
+	for _, button := range me.headerButtons {
+		widths = append(widths, button.W())
+	}
+	me.packagesBrowser.SetColumnWidths(widths...)
 }
 
 func (me *App) onSelectPackage() {
 	if i := me.packagesBrowser.Value(); i > 0 {
-		if text := me.packagesBrowser.Text(i); text != "" {
-			if j := strings.IndexByte(text, '\t'); j > -1 {
-				text = text[:j]
-				if j := strings.Index(text, "@."); j > -1 {
-					if text = text[j+2:]; text != "" {
-						me.showDescription(text)
-					}
-				}
-			}
+		if name, ok := me.packagesBrowser.Data(i).(string); ok {
+			me.showDescription(name)
 		}
 	}
 }
 
+// showDescription shows the package's details with the words that the
+// last search matched highlighted.
 func (me *App) showDescription(name string) {
 	if deb, ok := me.model.Debs[name]; ok {
+		explanation := ds.Explanation{}
+		if me.lastQuery != nil {
+			explanation = me.lastQuery.Explain(deb)
+		}
 		me.descView.SetValue(fmt.Sprintf(descTemplate,
-			deb.Url, html.EscapeString(deb.Name),
+			deb.Url, highlighted(deb.Name,
+				explanation.MatchesIn(ds.NameField)),
 			html.EscapeString(deb.Version), ds.HumanSize(deb.Size),
-			html.EscapeString(deb.ShortDesc),
-			html.EscapeString(deb.LongDesc)))
+			highlighted(deb.ShortDesc,
+				explanation.MatchesIn(ds.ShortDescField)),
+			deb.LongDesc.Html(explanation.MatchesIn(ds.LongDescField),
+				matchStart, matchEnd)))
 	}
 }
 
@@ -148,6 +178,16 @@ func (me *App) onAbout() {
 
 }
 
+func (me *App) onWarnings() {
+	if me.model == nil || len(me.model.Warnings) == 0 {
+		return
+	}
+	form := gui.MakeInfoForm("Warnings", appName,
+		warningsHtml(me.model.Warnings), iconSvg, 600, 300,
+		me.config.TextSize, true)
+	form.Show()
+}
+
 func (me *App) onHelp() {
 	form := gui.MakeInfoForm("Help", appName, helpHtml, iconSvg, 600, 550,
 		me.config.TextSize, true)
@@ -155,12 +195,16 @@ func (me *App) onHelp() {
 }
 
 func (me *App) onQuit() {
+	if me.cancelLoad != nil {
+		me.cancelLoad()
+	}
 	me.config.X = me.Window.X()
 	me.config.Y = me.Window.Y()
 	me.config.Width = me.Window.W()
 	me.config.Height = me.Window.H()
 	me.config.Scale = fltk.ScreenScale(0)
-	me.config.IncludeNonFreeSections = me.incNonFreeCheckbox.Value()
+	me.config.Components = strings.Join(me.components.ToSortedSlice(), ",")
+	me.config.HiddenKinds = strings.Join(me.hiddenKindNames(), ",")
 	me.config.AllTags = me.tagsMatchAllRadioButton.Value()
 	me.config.AllWords = me.wordsMatchAllRadioButton.Value()
 	me.config.save()
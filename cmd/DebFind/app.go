@@ -4,12 +4,15 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"slices"
 	"strings"
 
 	ds "github.com/mark-summerfield/debsearch"
 	"github.com/mark-summerfield/debsearch/cmd/DebFind/gui"
 	"github.com/mark-summerfield/gong"
+	"github.com/mark-summerfield/gset"
 	"github.com/pwiecz/go-fltk"
 )
 
@@ -17,10 +20,16 @@ type App struct {
 	*fltk.Window
 	config                   *Config
 	model                    *ds.Model
+	vocabulary               *ds.Vocabulary
+	expandedFacets           gset.Set[string]
+	tagsAndCounts            map[string]int
+	components               gset.Set[string] // empty means any
+	hiddenKinds              gset.Set[ds.Kind]
 	mainVBox                 *fltk.Flex
+	buttonPanel              *fltk.Flex
 	sectionsLabel            *fltk.Button
 	sectionsBrowser          *fltk.MultiBrowser
-	incNonFreeCheckbox       *fltk.CheckButton
+	componentsButton         *fltk.MenuButton
 	tagsLabel                *fltk.Button
 	tagsBrowser              *fltk.MultiBrowser
 	tagsMatchAllRadioButton  *fltk.RadioRoundButton
@@ -28,13 +37,28 @@ type App struct {
 	wordsInput               *fltk.Input
 	wordsMatchAllRadioButton *fltk.RadioRoundButton
 	wordsMatchAnyRadioButton *fltk.RadioRoundButton
+	kindsButton              *fltk.MenuButton
 	packagesLabel            *fltk.Button
+	packagesHeader           *fltk.Flex
+	headerButtons            []*fltk.Button
 	packagesBrowser          *fltk.HoldBrowser
+	lastQuery                *ds.Query // to re-sort when a header's clicked
 	descView                 *fltk.HelpView
+	progress                 *fltk.Progress
+	warningsButton           *fltk.Button
+	cancelLoad               context.CancelFunc
 }
 
 func newApp(config *Config) *App {
-	app := &App{Window: nil, config: config}
+	app := &App{Window: nil, config: config,
+		vocabulary: ds.DefaultVocabulary(), expandedFacets: gset.New[string](),
+		components:  gset.New(commaSplit(config.Components)...),
+		hiddenKinds: gset.New[ds.Kind]()}
+	for _, name := range commaSplit(config.HiddenKinds) {
+		if kind, err := ds.ParseKind(name); err == nil {
+			app.hiddenKinds.Add(kind)
+		}
+	}
 	app.makeMainWindow()
 	app.makeWidgets()
 	app.Window.End()
@@ -42,44 +66,85 @@ func newApp(config *Config) *App {
 	return app
 }
 
+// loadPackages reads the packages in a goroutine (cancelling any load
+// already in progress) and updates the progress bar as each file is read.
 func (me *App) loadPackages() {
-	pairs := ds.StdFilePairsWithDescriptions(me.config.Arc)
-	if model, err := ds.NewModel(pairs...); err != nil {
-		me.onError(err)
-	} else {
-		me.model = &model
-		me.onHtmlMessage(fmt.Sprintf(loadTemplate,
-			gong.Commas(len(model.Debs))))
-		me.populateSections()
-		me.populateTags()
+	if me.cancelLoad != nil {
+		me.cancelLoad()
 	}
+	ctx, cancel := context.WithCancel(context.Background())
+	me.cancelLoad = cancel
+	pairs := ds.StdFilePairsWithDescriptionsIn(me.config.ListsDir,
+		me.config.Arc)
+	sources := make([]ds.Source, 0, len(pairs))
+	for _, pair := range pairs {
+		sources = append(sources, pair)
+	}
+	me.onInfo("Reading packages…")
+	me.progress.SetValue(0)
+	me.progress.SetLabel("")
+	me.progress.Show()
+	options := ds.LoadOptions{Progress: func(progress ds.Progress) {
+		fltk.Awake(func() {
+			if ctx.Err() == nil {
+				me.onLoadProgress(progress)
+			}
+		})
+	}}
+	go func() {
+		model, err := ds.NewModelContext(ctx, options, sources...)
+		fltk.Awake(func() {
+			if ctx.Err() == nil { // not superseded by a later load
+				me.onLoaded(model, err)
+			}
+		})
+	}()
 }
 
-func (me *App) populateSections() {
-	me.sectionsBrowser.Clear()
-	count := 0
-	for _, section := range gong.SortedMapKeys(me.model.SectionsAndCounts) {
-		if !strings.HasPrefix(section, nonfreePrefix) &&
-			!strings.HasSuffix(section, todoSuffix) {
-			me.sectionsBrowser.Add(fmt.Sprintf("%s (%s)", section,
-				gong.Commas(me.model.SectionsAndCounts[section])))
-			count++
-		}
-	}
-	me.updateSectionsLabel(0)
+func (me *App) onLoadProgress(progress ds.Progress) {
+	me.progress.SetMaximum(float64(progress.SourcesTotal))
+	me.progress.SetValue(float64(progress.SourcesDone))
+	me.progress.SetLabel(fmt.Sprintf("%s pkgs",
+		gong.Commas(progress.PackagesParsed)))
 }
 
-func (me *App) populateTags() {
-	me.tagsBrowser.Clear()
-	count := 0
-	for _, tag := range gong.SortedMapKeys(me.model.TagsAndCounts) {
-		if !strings.HasSuffix(tag, todoSuffix) {
-			me.tagsBrowser.Add(fmt.Sprintf("%s (%s)", tag,
-				gong.Commas(me.model.TagsAndCounts[tag])))
-			count++
+func (me *App) onLoaded(model ds.Model, err error) {
+	me.cancelLoad = nil
+	me.progress.Hide()
+	if err != nil {
+		me.warningsButton.Hide()
+		me.onError(err)
+	} else {
+		me.model = &model
+		warnings := ""
+		if len(model.Warnings) == 0 {
+			me.warningsButton.Hide()
+		} else {
+			warnings = fmt.Sprintf(loadWarningsTemplate,
+				len(model.Warnings))
+			me.warningsButton.SetLabel(fmt.Sprintf("Warnin&gs (%d)…",
+				len(model.Warnings)))
+			me.warningsButton.Show()
 		}
+		me.onHtmlMessage(fmt.Sprintf(loadTemplate,
+			gong.Commas(len(model.Debs)), warnings))
+		me.populateComponents()
+		me.populateKinds()
+		me.populateSections(model.SectionsAndCounts)
+		me.populateTags(model.TagsAndCounts)
 	}
-	me.updateTagsLabel(0)
+	me.buttonPanel.Layout()
+}
+
+// populateSections fills the sections browser with every section and
+// the given counts (the model's or those of the last search's results).
+func (me *App) populateSections(sectionsAndCounts map[string]int) {
+	count := populateBrowser(me.sectionsBrowser,
+		me.model.SectionsAndCounts, sectionsAndCounts,
+		me.config.HideZeroCounts, func(section string) bool {
+			return !strings.HasSuffix(section, todoSuffix)
+		})
+	me.updateSectionsLabel(count)
 }
 
 func (me *App) makeMainWindow() {
@@ -119,6 +184,13 @@ func (me *App) makeButtonPanel(width, y int) *fltk.Flex {
 	hbox.Fixed(findButton, buttonWidth)
 	x += buttonWidth
 	fltk.NewBox(fltk.FLAT_BOX, x, 0, buttonWidth, gui.ButtonHeight)
+	me.progress = fltk.NewProgress(x, 0, buttonWidth, gui.ButtonHeight)
+	me.progress.SetSelectionColor(fltk.BLUE)
+	me.warningsButton = fltk.NewButton(x, 0, buttonWidth, gui.ButtonHeight)
+	me.warningsButton.SetLabelColor(fltk.DARK_RED)
+	me.warningsButton.SetCallback(me.onWarnings)
+	me.warningsButton.Hide()
+	hbox.Fixed(me.warningsButton, buttonWidth)
 	x += buttonWidth
 	configButton := makeButton(x, " &Options…", configSvg, me.onConfigure)
 	hbox.Fixed(configButton, buttonWidth)
@@ -132,6 +204,7 @@ func (me *App) makeButtonPanel(width, y int) *fltk.Flex {
 	quitButton := makeButton(x, " &Quit", quitSvg, me.onQuit)
 	hbox.Fixed(quitButton, buttonWidth)
 	hbox.End()
+	me.buttonPanel = hbox
 	return hbox
 }
 
@@ -177,14 +250,104 @@ func (me *App) makeSectionsPanel(x, y, width, height int) {
 	})
 	hbox.Fixed(clearSectionsButton, buttonWidth)
 	padBox(hbox, gui.Margin)
-	me.incNonFreeCheckbox = fltk.NewCheckButton(labelWidth, 0, labelWidth,
-		gui.ButtonHeight, "Pl&us Non-Free")
-	me.incNonFreeCheckbox.SetValue(me.config.IncludeNonFreeSections)
+	me.componentsButton = fltk.NewMenuButton(labelWidth, 0, labelWidth,
+		gui.ButtonHeight)
+	me.componentsButton.SetTooltip("Choose the components, e.g., main, " +
+		"contrib, non-free, to search; choose none to search them all.")
+	me.updateComponentsLabel()
 	hbox.End()
 	vbox.Fixed(hbox, gui.ButtonHeight)
 	vbox.End()
 }
 
+// populateComponents replaces the components menu's items with the
+// model's components, each checked if it is one of those chosen (chosen
+// components that the model doesn't have are dropped).
+func (me *App) populateComponents() {
+	for me.componentsButton.Size() > 1 { // keep the terminator
+		me.componentsButton.Remove(0)
+	}
+	for component := range me.components {
+		if _, ok := me.model.ComponentsAndCounts[component]; !ok {
+			me.components.Delete(component)
+		}
+	}
+	for _, component := range gong.SortedMapKeys(
+		me.model.ComponentsAndCounts) {
+		component := component
+		flags := fltk.MENU_TOGGLE
+		if me.components.Contains(component) {
+			flags |= fltk.MENU_VALUE
+		}
+		me.componentsButton.AddEx(fmt.Sprintf("%s (%s)", component,
+			gong.Commas(me.model.ComponentsAndCounts[component])), 0,
+			func() { me.onComponent(component) }, flags)
+	}
+	me.updateComponentsLabel()
+}
+
+func (me *App) onComponent(component string) {
+	if me.components.Contains(component) {
+		me.components.Delete(component)
+	} else {
+		me.components.Add(component)
+	}
+	me.updateComponentsLabel()
+}
+
+func (me *App) updateComponentsLabel() {
+	components := "any"
+	if !me.components.IsEmpty() {
+		components = strings.Join(me.components.ToSortedSlice(), ", ")
+	}
+	me.componentsButton.SetLabel("Incl&ude: " + components)
+}
+
+// populateKinds replaces the kinds menu's items with every kind and how
+// many of the model's packages are of it, each checked if it is hidden.
+func (me *App) populateKinds() {
+	for me.kindsButton.Size() > 1 { // keep the terminator
+		me.kindsButton.Remove(0)
+	}
+	for _, name := range ds.KindNames() {
+		kind, _ := ds.ParseKind(name)
+		flags := fltk.MENU_TOGGLE
+		if me.hiddenKinds.Contains(kind) {
+			flags |= fltk.MENU_VALUE
+		}
+		me.kindsButton.AddEx(fmt.Sprintf("%s (%s)", name,
+			gong.Commas(me.model.KindsAndCounts[name])), 0,
+			func() { me.onKind(kind) }, flags)
+	}
+	me.updateKindsLabel()
+}
+
+func (me *App) onKind(kind ds.Kind) {
+	if me.hiddenKinds.Contains(kind) {
+		me.hiddenKinds.Delete(kind)
+	} else {
+		me.hiddenKinds.Add(kind)
+	}
+	me.updateKindsLabel()
+}
+
+func (me *App) updateKindsLabel() {
+	kinds := "none"
+	if !me.hiddenKinds.IsEmpty() {
+		kinds = strings.Join(me.hiddenKindNames(), ", ")
+	}
+	me.kindsButton.SetLabel("Hi&de: " + kinds)
+}
+
+func (me *App) hiddenKindNames() []string {
+	names := make([]string, 0, len(me.hiddenKinds))
+	for kind := range me.hiddenKinds {
+		names = append(names, kind.String())
+	}
+	slices.Sort(names)
+	return names
+}
+
 func (me *App) updateSectionsLabel(count int) {
 	me.sectionsLabel.SetLabel(fmt.Sprintf("&Sections (%s/%s)",
 		gong.Commas(count), gong.Commas(me.sectionsBrowser.Size())))
@@ -199,9 +362,11 @@ func (me *App) makeTagsPanel(x, y, width, height int) {
 	vbox.Fixed(me.tagsLabel, gui.LabelHeight())
 	me.tagsBrowser = fltk.NewMultiBrowser(0, gui.ButtonHeight, width,
 		height)
-	me.tagsBrowser.SetCallback(func() {
-		me.updateTagsLabel(selectedCount(me.tagsBrowser))
-	})
+	me.tagsBrowser.SetTooltip("Double-click a facet to show or hide " +
+		"its tags; select a facet to match any of its tags.")
+	me.tagsBrowser.SetCallbackCondition(fltk.WhenChanged |
+		fltk.WhenNotChanged)
+	me.tagsBrowser.SetCallback(me.onTagsBrowser)
 	me.tagsLabel.SetCallback(func() { me.tagsBrowser.TakeFocus() })
 	hbox := gui.MakeHBox(x, height-(2*gui.ButtonHeight), width,
 		gui.ButtonHeight)
@@ -258,11 +423,37 @@ func (me *App) makeWordsPanel(x, y, width, height int) {
 	me.wordsMatchAnyRadioButton = fltk.NewRadioRoundButton(x, 0,
 		gui.LabelWidth, gui.ButtonHeight, "An&y")
 	me.wordsMatchAnyRadioButton.SetValue(!me.config.AllWords)
+	hbox.Fixed(me.wordsMatchAnyRadioButton, gui.LabelWidth)
+	padBox(hbox, gui.Margin)
+	me.kindsButton = fltk.NewMenuButton(x, 0, gui.LabelWidth,
+		gui.ButtonHeight)
+	me.kindsButton.SetTooltip("Choose the kinds of package to hide, " +
+		"e.g., debug symbols, documentation, and transitional packages.")
+	me.updateKindsLabel()
 	hbox.End()
 	vbox.Fixed(hbox, gui.ButtonHeight)
 	vbox.End()
 }
 
+// packageColumn is one of the packages browser's columns; clicking its
+// header sorts by its key (or reverses the order if it is already sorted
+// by its key).
+type packageColumn struct {
+	title   string
+	sortBy  ds.SortKey
+	width   int // the last column takes the remaining width
+	tooltip string
+}
+
+var packageColumns = []packageColumn{
+	{"Name", ds.SortByName, 200, "Sort by name."},
+	{"Section", ds.SortBySection, 110, "Sort by component and section."},
+	{"Version", ds.SortByVersion, 110, "Sort by version, oldest first."},
+	{"Size", ds.SortBySize, 70, "Sort by installed size, smallest first."},
+	{"Description", ds.SortByRelevance, 0,
+		"Sort by relevance to the Words, most relevant first."},
+}
+
 func (me *App) makeResultPanel(x, y, width, height int) {
 	labelHeight := gui.LabelHeight()
 	tile := fltk.NewTile(x, y, width, height)
@@ -271,8 +462,10 @@ func (me *App) makeResultPanel(x, y, width, height int) {
 	me.packagesLabel = gui.MakeAccelLabel(width, labelHeight,
 		"&Packages Found")
 	vbox.Fixed(me.packagesLabel, labelHeight)
+	me.makePackagesHeader(width)
+	vbox.Fixed(me.packagesHeader, gui.ButtonHeight)
 	me.packagesBrowser = fltk.NewHoldBrowser(0, labelHeight, width,
-		height-labelHeight)
+		height-labelHeight-gui.ButtonHeight)
 	me.packagesBrowser.SetCallback(me.onSelectPackage)
 	vbox.End()
 	me.packagesLabel.SetCallback(func() { me.packagesBrowser.TakeFocus() })
@@ -286,11 +479,45 @@ func (me *App) makeResultPanel(x, y, width, height int) {
 	me.descView.TextFont(fltk.HELVETICA)
 	me.descView.TextSize(me.config.TextSize)
 	label.SetCallback(func() { me.descView.TakeFocus() })
-	me.onInfo("Reading packages…")
 	vbox.End()
 	tile.End()
 }
 
+func (me *App) makePackagesHeader(width int) {
+	me.packagesHeader = gui.MakeHBox(0, 0, width, gui.ButtonHeight)
+	for _, column := range packageColumns {
+		column := column
+		button := fltk.NewButton(0, 0, column.width, gui.ButtonHeight)
+		button.SetBox(fltk.THIN_UP_BOX)
+		button.SetAlign(fltk.ALIGN_INSIDE | fltk.ALIGN_LEFT)
+		button.ClearVisibleFocus()
+		button.SetTooltip(column.tooltip)
+		button.SetCallback(func() { me.onSort(column.sortBy) })
+		if column.width > 0 {
+			me.packagesHeader.Fixed(button, column.width)
+		}
+		me.headerButtons = append(me.headerButtons, button)
+	}
+	me.packagesHeader.End()
+	me.updatePackagesHeader()
+}
+
+// updatePackagesHeader marks the column the packages are sorted by with
+// an arrow showing the direction.
+func (me *App) updatePackagesHeader() {
+	for i, column := range packageColumns {
+		label := column.title
+		if column.sortBy.String() == me.config.SortBy {
+			if me.config.SortReverse {
+				label += " ▼"
+			} else {
+				label += " ▲"
+			}
+		}
+		me.headerButtons[i].SetLabel(label)
+	}
+}
+
 func (me *App) updatePackagesLabel(count int) {
 	me.packagesLabel.SetLabel(fmt.Sprintf("&Packages Found (%s)",
 		gong.Commas(count)))
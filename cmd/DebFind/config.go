@@ -16,25 +16,32 @@ import (
 )
 
 type Config struct {
-	filename               string
-	debug                  bool
-	X                      int
-	Y                      int
-	Width                  int
-	Height                 int
-	Scale                  float32
-	TextSize               int
-	IncludeNonFreeSections bool
-	Arc                    string
-	AllTags                bool
-	AllWords               bool
+	filename       string
+	debug          bool
+	X              int
+	Y              int
+	Width          int
+	Height         int
+	Scale          float32
+	TextSize       int
+	Components     string // comma-separated; "" means any
+	HiddenKinds    string // comma-separated ds.Kind names
+	Arc            string
+	ListsDir       string
+	AllTags        bool
+	AllWords       bool
+	HideZeroCounts bool   // after a search
+	SortBy         string // a ds.SortKey name
+	SortReverse    bool
 }
 
 func newConfig() *Config {
 	filename, found := gong.GetIniFile(domain, appName)
 	config := &Config{filename: filename, X: -1, Width: 800, Height: 600,
-		Scale: 1.0, TextSize: 14, Arc: ds.DefaultArc, AllTags: true,
-		AllWords: true}
+		Scale: 1.0, TextSize: 14, Arc: ds.DefaultArc,
+		SortBy:      ds.SortByName.String(),
+		HiddenKinds: defaultHiddenKinds,
+		ListsDir:    ds.ListsPath, AllTags: true, AllWords: true}
 	if found {
 		cfg, err := ini.Load(filename)
 		if err != nil {
@@ -58,6 +65,12 @@ func newConfig() *Config {
 					config.TextSize > 20 {
 					config.TextSize = 14
 				}
+				if config.ListsDir == "" {
+					config.ListsDir = ds.ListsPath
+				}
+				if _, err := ds.ParseSortKey(config.SortBy); err != nil {
+					config.SortBy = ds.SortByName.String()
+				}
 			}
 		}
 	}
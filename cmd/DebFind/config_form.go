@@ -13,15 +13,17 @@ import (
 
 type configForm struct {
 	*fltk.Window
-	width      int
-	height     int
-	labelWidth int
-	app        *App
-	arcChoice  *fltk.Choice
+	width          int
+	height         int
+	labelWidth     int
+	app            *App
+	arcChoice      *fltk.Choice
+	listsInput     *fltk.Input
+	hideZeroButton *fltk.CheckButton
 }
 
 func newConfigForm(app *App) configForm {
-	form := configForm{width: 260, height: 145, app: app}
+	form := configForm{width: 360, height: 212, app: app}
 	form.Window = fltk.NewWindow(form.width, form.height)
 	form.Window.SetLabel("Configure — " + appName)
 	gui.AddWindowIcon(form.Window, iconSvg)
@@ -39,6 +41,10 @@ func (me *configForm) makeWidgets() {
 	vbox.Fixed(hbox, rowHeight)
 	hbox = me.makeArcRow()
 	vbox.Fixed(hbox, rowHeight)
+	hbox = me.makeListsDirRow()
+	vbox.Fixed(hbox, rowHeight)
+	hbox = me.makeHideZeroRow()
+	vbox.Fixed(hbox, rowHeight)
 	hbox = me.makeButtonRow()
 	vbox.Fixed(hbox, rowHeight)
 	vbox.End()
@@ -107,6 +113,35 @@ func (me *configForm) makeArcRow() *fltk.Flex {
 	return hbox
 }
 
+func (me *configForm) makeListsDirRow() *fltk.Flex {
+	hbox := gui.MakeHBox(0, 0, me.width, gui.ButtonHeight)
+	listsLabel := gui.MakeAccelLabel(me.labelWidth, gui.ButtonHeight,
+		"&Lists Dir")
+	me.listsInput = fltk.NewInput(0, 0, me.width-me.labelWidth,
+		gui.ButtonHeight)
+	me.listsInput.SetTooltip("The apt lists directory to read, e.g., " +
+		"a chroot's or a mirror snapshot's lists.")
+	me.listsInput.SetValue(me.app.config.ListsDir)
+	listsLabel.SetCallback(func() { me.listsInput.TakeFocus() })
+	hbox.Fixed(listsLabel, me.labelWidth)
+	hbox.End()
+	return hbox
+}
+
+func (me *configForm) makeHideZeroRow() *fltk.Flex {
+	hbox := gui.MakeHBox(0, 0, me.width, gui.ButtonHeight)
+	padding := fltk.NewBox(fltk.FLAT_BOX, 0, 0, me.labelWidth,
+		gui.ButtonHeight)
+	me.hideZeroButton = fltk.NewCheckButton(0, 0,
+		me.width-me.labelWidth, gui.ButtonHeight, "&Hide Zero Counts")
+	me.hideZeroButton.SetTooltip("After a search, only list the " +
+		"sections and tags that some of the found packages have.")
+	me.hideZeroButton.SetValue(me.app.config.HideZeroCounts)
+	hbox.Fixed(padding, me.labelWidth)
+	hbox.End()
+	return hbox
+}
+
 func (me *configForm) makeButtonRow() *fltk.Flex {
 	buttonWidth := gui.ButtonWidth()
 	hbox := gui.MakeHBox(0, 0, me.width, rowHeight)
@@ -126,10 +161,17 @@ func (me *configForm) makeButtonRow() *fltk.Flex {
 func (me *configForm) onClose() {
 	oldArc := me.app.config.Arc
 	newArc := me.arcChoice.SelectedText()
-	if oldArc != newArc {
+	oldListsDir := me.app.config.ListsDir
+	newListsDir := strings.TrimSpace(me.listsInput.Value())
+	if newListsDir == "" {
+		newListsDir = ds.ListsPath
+	}
+	if oldArc != newArc || oldListsDir != newListsDir {
 		me.app.config.Arc = newArc
+		me.app.config.ListsDir = newListsDir
 		me.app.loadPackages()
 	}
+	me.app.config.HideZeroCounts = me.hideZeroButton.Value()
 	me.app.descView.TextSize(me.app.config.TextSize)
 	me.Window.Destroy()
 }
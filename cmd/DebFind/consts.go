@@ -30,28 +30,33 @@ const (
 	url         = "https://github.com/mark-summerfield/debsearch"
 	author      = "Mark Summerfield"
 
-	tinyTimeout   = 0.005
-	rowHeight     = 32
-	colWidth      = 80
-	nonfreePrefix = "non-free/"
-	todoSuffix    = "/TODO"
-	light1        = 255
-	light2        = 52
-	iconSize      = 22
+	tinyTimeout = 0.005
+	rowHeight   = 32
+	colWidth    = 80
+	todoSuffix  = "/TODO"
+	light1      = 255
+	light2      = 52
+	iconSize    = 22
+	snippetGray = 39 // FL_DARK3
+
+	defaultHiddenKinds = "debug,transitional" // ds.Kind names
 
 	loadTemplate = `<html><body>
-<font color=navy>Read %s packages.</font>
+<font color=navy>Read %s packages.</font>%s
 <p><font color=green>Click <b><u>F</u>ind</b> to find matching
 packages…</font></p>
 </body></html>`
 
+	loadWarningsTemplate = `
+<p><font color=maroon>%d source(s) could not be fully read; click
+<b>Warnin<u>g</u>s</b> for details.</font></p>`
+
 	descTemplate = `<html><body>
 <a href="%s"><font color=navy>%s</font></a>&nbsp;&nbsp;v%s&nbsp;&nbsp;%s
 <p><font color=green>%s</font></p>
-<p>
-<pre><font face=helvetica>
 %s
-</font></pre>
-</p>
 </body></html>`
+
+	matchStart = "<b><font color=maroon>"
+	matchEnd   = "</font></b>"
 )
@@ -0,0 +1,127 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	ds "github.com/mark-summerfield/debsearch"
+	"github.com/mark-summerfield/gong"
+	"github.com/mark-summerfield/gset"
+	"github.com/pwiecz/go-fltk"
+)
+
+// populateTags fills the tags browser as a tree: a bold row for each
+// debtags facet (whose name is "facet/*" so that selecting it matches any
+// of the facet's tags) followed, if the facet is expanded, by its tags
+// with the given counts (the model's or those of the last search's
+// results). Selected tags are shown even if their facet is collapsed.
+func (me *App) populateTags(tagsAndCounts map[string]int) {
+	me.tagsAndCounts = tagsAndCounts
+	wasSelected := gset.New(selected(me.tagsBrowser)...)
+	topLine := me.tagsBrowser.TopLine()
+	me.tagsBrowser.Clear()
+	count := 0
+	add := func(text, name string) {
+		me.tagsBrowser.AddWithData(text, name)
+		if wasSelected.Contains(name) {
+			me.tagsBrowser.SetSelected(me.tagsBrowser.Size(), true)
+			count++
+		}
+	}
+	tagsForFacet := me.model.TagsByFacet()
+	for _, facet := range gong.SortedMapKeys(tagsForFacet) {
+		tags := me.visibleTags(tagsForFacet[facet], wasSelected)
+		indent := ""
+		if facet != "" {
+			name := facet + "/*"
+			if len(tags) == 0 && !wasSelected.Contains(name) {
+				continue
+			}
+			expanded := me.expandedFacets.Contains(facet)
+			add(me.facetText(facet, len(tags), expanded), name)
+			if !expanded {
+				tags = wasSelected.Intersection(gset.New(tags...)).
+					ToSortedSlice()
+			}
+			indent = "    "
+		}
+		for _, tag := range tags {
+			_, name := ds.SplitTag(tag)
+			add(fmt.Sprintf("@.%s%s (%s)", indent, name,
+				gong.Commas(tagsAndCounts[tag])), tag)
+		}
+	}
+	_ = me.tagsBrowser.SetTopLine(min(topLine, me.tagsBrowser.Size()))
+	me.updateTagsLabel(count)
+}
+
+// visibleTags returns those of the facet's tags which should be shown.
+func (me *App) visibleTags(tags []string,
+	wasSelected gset.Set[string]) []string {
+	visible := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if !strings.HasSuffix(tag, todoSuffix) && (wasSelected.Contains(
+			tag) || me.tagsAndCounts[tag] > 0 ||
+			!me.config.HideZeroCounts) {
+			visible = append(visible, tag)
+		}
+	}
+	return visible
+}
+
+func (me *App) facetText(facet string, count int, expanded bool) string {
+	marker := "▸"
+	if expanded {
+		marker = "▾"
+	}
+	text := fmt.Sprintf("@b@.%s %s", marker, facet)
+	if desc := me.vocabulary.Describe(facet); desc != "" {
+		text += " — " + desc
+	}
+	return fmt.Sprintf("%s (%s)", text, gong.Commas(count))
+}
+
+// onTagsBrowser sets the browser's tooltip to the clicked tag's or facet's
+// description, and expands or collapses a facet that is double-clicked.
+func (me *App) onTagsBrowser() {
+	if line := me.tagsBrowser.Value(); line > 0 {
+		name, _ := me.tagsBrowser.Data(line).(string)
+		me.tagsBrowser.SetTooltip(me.tagDescription(name))
+		if facet, ok := ds.IsFacetQuery(name); ok && fltk.EventClicks() > 0 {
+			me.tagsBrowser.SetSelected(line, false) // just toggle
+			if me.expandedFacets.Contains(facet) {
+				me.expandedFacets.Delete(facet)
+			} else {
+				me.expandedFacets.Add(facet)
+			}
+			me.populateTags(me.tagsAndCounts)
+			return
+		}
+	}
+	me.updateTagsLabel(selectedCount(me.tagsBrowser))
+}
+
+// tagDescription returns the vocabulary's description of the tag or
+// "facet/*", or the name itself if it has none.
+func (me *App) tagDescription(name string) string {
+	shortDesc, longDesc := "", ""
+	if facet, ok := ds.IsFacetQuery(name); ok {
+		if info, ok := me.vocabulary.Facets[facet]; ok {
+			shortDesc, longDesc = info.ShortDesc, info.LongDesc
+		}
+		name = facet
+	} else if info, ok := me.vocabulary.Tags[name]; ok {
+		shortDesc, longDesc = info.ShortDesc, info.LongDesc
+	}
+	switch {
+	case shortDesc == "":
+		return name
+	case longDesc == "":
+		return fmt.Sprintf("%s: %s", name, shortDesc)
+	default:
+		return fmt.Sprintf("%s: %s\n%s", name, shortDesc, longDesc)
+	}
+}
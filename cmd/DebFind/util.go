@@ -4,32 +4,72 @@
 package main
 
 import (
+	"fmt"
+	"html"
 	"strings"
 
+	ds "github.com/mark-summerfield/debsearch"
 	"github.com/mark-summerfield/debsearch/cmd/DebFind/gui"
+	"github.com/mark-summerfield/gong"
+	"github.com/mark-summerfield/gset"
 	"github.com/pwiecz/go-fltk"
 )
 
+func commaSplit(text string) []string {
+	items := []string{}
+	for _, item := range strings.Split(text, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func selectOrClear(browser *fltk.MultiBrowser, sel bool) {
 	for i := 1; i <= browser.Size(); i++ {
 		browser.SetSelected(i, sel)
 	}
 }
 
+// selected returns the names of the selected items; each item's name is
+// its data (its text is the name with its count).
 func selected(browser *fltk.MultiBrowser) []string {
 	selected := []string{}
 	for i := 1; i <= browser.Size(); i++ {
 		if browser.IsSelected(i) {
-			text := browser.Text(i)
-			if i := strings.IndexByte(text, '('); i > -1 {
-				text = strings.TrimSpace(text[:i])
+			if name, ok := browser.Data(i).(string); ok {
+				selected = append(selected, name)
 			}
-			selected = append(selected, text)
 		}
 	}
 	return selected
 }
 
+// populateBrowser replaces the browser's items with the names that keep
+// accepts, each with its count in namesAndCounts. Selected names stay
+// selected (and are shown even if their count is zero and hideZero is
+// true). Returns how many names are selected.
+func populateBrowser(browser *fltk.MultiBrowser, names,
+	namesAndCounts map[string]int, hideZero bool,
+	keep func(name string) bool) int {
+	wasSelected := gset.New(selected(browser)...)
+	browser.Clear()
+	count := 0
+	for _, name := range gong.SortedMapKeys(names) {
+		n := namesAndCounts[name]
+		isSelected := wasSelected.Contains(name)
+		if keep(name) && (n > 0 || !hideZero || isSelected) {
+			browser.AddWithData(fmt.Sprintf("%s (%s)", name,
+				gong.Commas(n)), name)
+			if isSelected {
+				browser.SetSelected(browser.Size(), true)
+				count++
+			}
+		}
+	}
+	return count
+}
+
 func selectedCount(browser *fltk.MultiBrowser) int {
 	count := 0
 	for i := 1; i <= browser.Size(); i++ {
@@ -59,3 +99,32 @@ func makeButton(x int, text, icon string, callback func()) *fltk.Button {
 	button.SetCallback(callback)
 	return button
 }
+
+// highlighted returns the text HTML-escaped with the matched words shown
+// in bold.
+func highlighted(text string, matches []ds.WordMatch) string {
+	var result strings.Builder
+	pos := 0
+	for _, match := range matches {
+		result.WriteString(html.EscapeString(text[pos:match.Start]))
+		result.WriteString(matchStart)
+		result.WriteString(html.EscapeString(text[match.Start:match.End]))
+		result.WriteString(matchEnd)
+		pos = match.End
+	}
+	result.WriteString(html.EscapeString(text[pos:]))
+	return result.String()
+}
+
+func warningsHtml(warnings []*ds.LoadError) string {
+	var text strings.Builder
+	text.WriteString("<html><body><font color=maroon><ul>\n")
+	for _, warning := range warnings {
+		text.WriteString("<li>")
+		text.WriteString(strings.ReplaceAll(html.EscapeString(
+			warning.Error()), "\n", "<br>\n"))
+		text.WriteString("</li>\n")
+	}
+	text.WriteString("</ul></font></body></html>")
+	return text.String()
+}
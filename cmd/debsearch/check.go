@@ -0,0 +1,97 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mark-summerfield/clip"
+	ds "github.com/mark-summerfield/debsearch"
+)
+
+func checkMain(args []string) {
+	parser := newCommandParser("check", "Check the packages named in "+
+		"provisioning files (plain lists, dpkg --get-selections output, "+
+		"Dockerfiles' apt-get install commands, or Ansible apt tasks) "+
+		"against the packages for the arc. Missing and virtual packages "+
+		"and pinned versions that aren't available are problems (with "+
+		"any likely replacements); transitional packages and those for "+
+		"another arc are noted. Exits 1 if there are any problems.")
+	inputOpts := newInputOptions(&parser)
+	allOpt := parser.Flag("all", "Also print the packages that are ok.")
+	allOpt.SetShortName(clip.NoShortName)
+	parser.PositionalCount = clip.OneOrMorePositionals
+	parser.PositionalHelp = "The provisioning files to check; Dockerfiles " +
+		"are recognized by their names (Dockerfile* or *.dockerfile) and " +
+		"Ansible files by their suffixes (.yml or .yaml)."
+	parser.MustSetPositionalVarName("FILE")
+	if err := parser.ParseArgs(inputOpts.hoisted(args)); err != nil {
+		parser.OnError(err) // doesn't return
+	}
+	input := inputOpts.config(&parser)
+	model := input.readModel(false)
+	checker := ds.NewChecker(&model, input.arc)
+	count := 0
+	problems := 0
+	for _, filename := range parser.Positionals {
+		refs, err := ds.ReadPackageList(filename)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			problems++
+			continue
+		}
+		for _, ref := range refs {
+			result := checker.Check(ref)
+			count++
+			if result.Status.IsProblem() {
+				problems++
+			}
+			if result.Status != ds.CheckOk || allOpt.Value() {
+				fmt.Printf("%s:%d: %s: %s\n", filename, ref.Line, ref,
+					describeCheck(result, input.arc))
+			}
+		}
+	}
+	fmt.Printf("packages checked for %s: %d; problems: %d\n", input.arc,
+		count, problems)
+	if problems > 0 {
+		os.Exit(1)
+	}
+}
+
+func describeCheck(result ds.CheckResult, arc string) string {
+	var text string
+	switch result.Status {
+	case ds.CheckOk:
+		text = "ok v" + result.Deb.Version
+	case ds.CheckMissing:
+		text = "missing for " + arc
+	case ds.CheckVirtual:
+		text = "virtual"
+	case ds.CheckPinMissing:
+		text = "pinned version unavailable (available: v" +
+			result.Deb.Version + ")"
+	case ds.CheckTransitional:
+		text = "transitional"
+	case ds.CheckOtherArc:
+		text = "for " + result.Ref.Arch + " (not checked)"
+	}
+	if len(result.Replacements) > 0 {
+		names := make([]string, 0, len(result.Replacements))
+		for _, deb := range result.Replacements {
+			names = append(names, deb.Name)
+		}
+		switch result.Status {
+		case ds.CheckVirtual:
+			text += " (provided by: " + strings.Join(names, " | ") + ")"
+		case ds.CheckTransitional: // it depends on all of them
+			text += " (use: " + strings.Join(names, ", ") + ")"
+		default:
+			text += " (use: " + strings.Join(names, " | ") + ")"
+		}
+	}
+	return text
+}
@@ -0,0 +1,110 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mark-summerfield/clip"
+	ds "github.com/mark-summerfield/debsearch"
+)
+
+type relationOptions struct {
+	recommendsOpt *clip.FlagOption
+	suggestsOpt   *clip.FlagOption
+}
+
+func newRelationOptions(parser *clip.Parser) relationOptions {
+	options := relationOptions{}
+	options.recommendsOpt = parser.Flag("recommends", "Include "+
+		"Recommends as well as Pre-Depends and Depends.")
+	options.recommendsOpt.SetShortName(clip.NoShortName)
+	options.suggestsOpt = parser.Flag("suggests", "Include Suggests as "+
+		"well as Pre-Depends and Depends.")
+	options.suggestsOpt.SetShortName(clip.NoShortName)
+	return options
+}
+
+func (me relationOptions) kinds() []ds.RelationKind {
+	kinds := []ds.RelationKind{ds.PreDependsKind, ds.DependsKind}
+	if me.recommendsOpt.Value() {
+		kinds = append(kinds, ds.RecommendsKind)
+	}
+	if me.suggestsOpt.Value() {
+		kinds = append(kinds, ds.SuggestsKind)
+	}
+	return kinds
+}
+
+func dependsMain(args []string) {
+	parser := newCommandParser("depends", "Print the named package's "+
+		"dependencies, noting any that are virtual or unavailable.")
+	inputOpts := newInputOptions(&parser)
+	relationOpts := newRelationOptions(&parser)
+	parser.PositionalCount = clip.OnePositional
+	parser.MustSetPositionalVarName("NAME")
+	if err := parser.ParseArgs(inputOpts.hoisted(args)); err != nil {
+		parser.OnError(err) // doesn't return
+	}
+	input := inputOpts.config(&parser)
+	model := input.readModel(false)
+	name := parser.Positionals[0]
+	deb, ok := model.Debs[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "no package called %q\n", name)
+		os.Exit(1)
+	}
+	fmt.Printf("%s v%s\n", deb.Name, deb.Version)
+	for _, kind := range relationOpts.kinds() {
+		if groups := deb.Relations(kind); len(groups) > 0 {
+			fmt.Printf("%s:\n", kind)
+			for _, group := range groups {
+				fmt.Printf("  %s\n", describeGroup(&model, group))
+			}
+		}
+	}
+}
+
+func describeGroup(model *ds.Model, group []ds.Relation) string {
+	alternatives := make([]string, 0, len(group))
+	for _, relation := range group {
+		text := relation.String()
+		if _, ok := model.Debs[relation.Name]; !ok {
+			if providers := model.Providers(relation.Name); len(
+				providers) > 0 {
+				names := make([]string, 0, len(providers))
+				for _, provider := range providers {
+					names = append(names, provider.Name)
+				}
+				text += " [virtual: " + strings.Join(names, ", ") + "]"
+			} else {
+				text += " [unavailable]"
+			}
+		}
+		alternatives = append(alternatives, text)
+	}
+	return strings.Join(alternatives, " | ")
+}
+
+func rdependsMain(args []string) {
+	parser := newCommandParser("rdepends", "Print the packages that "+
+		"depend on the named package (or on any virtual package it "+
+		"provides).")
+	inputOpts := newInputOptions(&parser)
+	relationOpts := newRelationOptions(&parser)
+	parser.PositionalCount = clip.OnePositional
+	parser.MustSetPositionalVarName("NAME")
+	if err := parser.ParseArgs(inputOpts.hoisted(args)); err != nil {
+		parser.OnError(err) // doesn't return
+	}
+	input := inputOpts.config(&parser)
+	model := input.readModel(false)
+	for _, reverse := range model.ReverseRelations(parser.Positionals[0],
+		relationOpts.kinds()...) {
+		fmt.Printf("* %s v%s (%s)\n", reverse.Deb.Name, reverse.Deb.Version,
+			reverse.Kind)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/mark-summerfield/clip"
+	ds "github.com/mark-summerfield/debsearch"
+	"github.com/mark-summerfield/gong"
+)
+
+func diffMain(args []string) {
+	parser := newCommandParser("diff", "Report the packages added, "+
+		"removed, and whose version changed between two sets of "+
+		"packages, e.g., apt lists before and after apt update, or a "+
+		"mirror's bookworm and trixie suites, plus changes in section, "+
+		"tag, and component counts. Each set is read using the --old-* "+
+		"and --new-* options (which are the same as other commands' "+
+		"input options), or from the apt lists directories given as "+
+		"positionals. Exits with 1 if there are any differences.")
+	oldOpts := newSideInputOptions(&parser, "old-")
+	newOpts := newSideInputOptions(&parser, "new-")
+	jsonOpt := parser.Flag("json", "Output the differences as JSON.")
+	parser.PositionalCount = clip.ZeroOrMorePositionals
+	parser.PositionalHelp = "The old and new apt lists directories " +
+		"(the same as --old-lists-dir DIR --new-lists-dir DIR)."
+	parser.MustSetPositionalVarName("DIR")
+	if err := parser.ParseArgs(newOpts.hoisted(
+		oldOpts.hoisted(args))); err != nil {
+		parser.OnError(err) // doesn't return
+	}
+	oldInput := oldOpts.config(&parser)
+	newInput := newOpts.config(&parser)
+	switch len(parser.Positionals) {
+	case 0:
+	case 2:
+		if oldOpts.rootOpt.Given() || oldOpts.listsDirOpt.Given() ||
+			newOpts.rootOpt.Given() || newOpts.listsDirOpt.Given() {
+			parser.OnError(errors.New("can't use both DIR positionals " +
+				"and --old-/--new- root or lists-dir"))
+		}
+		oldInput.listsDir = parser.Positionals[0]
+		newInput.listsDir = parser.Positionals[1]
+	default:
+		parser.OnError(fmt.Errorf("expected zero or two DIRs, got %d",
+			len(parser.Positionals)))
+	}
+	if oldInput.stdin && newInput.stdin {
+		parser.OnError(errors.New(
+			"can't use both --old-stdin and --new-stdin"))
+	}
+	oldModel := oldInput.readModel(false)
+	newModel := newInput.readModel(false)
+	diff := oldModel.Diff(newModel)
+	if jsonOpt.Value() {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		gong.CheckError("failed to write JSON", encoder.Encode(diff))
+	} else {
+		printDiff(&diff)
+	}
+	if !diff.IsEmpty() {
+		os.Exit(1)
+	}
+}
+
+func printDiff(diff *ds.ModelDiff) {
+	for _, item := range diff.Added {
+		fmt.Printf("+ %s v%s\n", item.Name, item.Version)
+	}
+	for _, item := range diff.Removed {
+		fmt.Printf("- %s v%s\n", item.Name, item.Version)
+	}
+	for _, item := range diff.Changed {
+		fmt.Printf("~ %s v%s → v%s\n", item.Name, item.OldVersion,
+			item.NewVersion)
+	}
+	printCountChanges("Sections", diff.Sections)
+	printCountChanges("Tags", diff.Tags)
+	printCountChanges("Components", diff.Components)
+}
+
+func printCountChanges(title string, changes []ds.CountChange) {
+	if len(changes) > 0 {
+		fmt.Printf("%s (%d):\n", title, len(changes))
+		for _, change := range changes {
+			fmt.Printf("  %s %s → %s (%+d)\n", change.Name,
+				gong.Commas(change.OldCount), gong.Commas(change.NewCount),
+				change.NewCount-change.OldCount)
+		}
+	}
+}
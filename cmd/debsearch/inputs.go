@@ -0,0 +1,310 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/mark-summerfield/clip"
+	ds "github.com/mark-summerfield/debsearch"
+	"github.com/mark-summerfield/gong"
+)
+
+// inputOptions are the options shared by every mode that reads packages.
+type inputOptions struct {
+	arcOpt          *clip.StrOption
+	rootOpt         *clip.StrOption
+	listsDirOpt     *clip.StrOption
+	packagesOpt     *clip.StrOption
+	translationsOpt *clip.StrOption
+	mirrorOpt       *clip.StrOption
+	suitesOpt       *clip.StrOption
+	componentsOpt   *clip.StrOption
+	noVerifyOpt     *clip.FlagOption
+	stdinOpt        *clip.FlagOption
+	debDirOpt       *clip.StrOption
+	dpkgStatusOpt   *clip.FlagOption
+}
+
+func newInputOptions(parser *clip.Parser) *inputOptions {
+	return newSideInputOptions(parser, "")
+}
+
+// newSideInputOptions returns input options whose names start with the
+// given prefix (e.g., "old-" for --old-mirror), so that a command can read
+// more than one set of packages; prefixed options have no short names.
+func newSideInputOptions(parser *clip.Parser, prefix string) *inputOptions {
+	name := func(name string) string { return prefix + name }
+	options := &inputOptions{}
+	options.arcOpt = parser.Choice(name("arc"),
+		"System arc(hitecture) [default: "+ds.DefaultArc+"].",
+		strings.Fields(ds.Arcs), ds.DefaultArc)
+	options.rootOpt = parser.Str(name("root"), "Read the apt lists (and "+
+		"dpkg status) from the chroot or image root filesystem in DIR "+
+		"[default: /].", "")
+	options.rootOpt.MustSetVarName("DIR")
+	options.listsDirOpt = parser.Str(name("lists-dir"), "Read the apt "+
+		"lists from DIR [default: "+ds.ListsPath+"].", "")
+	options.listsDirOpt.SetShortName(clip.NoShortName)
+	options.listsDirOpt.MustSetVarName("DIR")
+	options.packagesOpt = parser.Str(name("packages"), "Read the given "+
+		"comma-separated Packages files instead of the apt lists.", "")
+	options.packagesOpt.MustSetVarName("FILES")
+	options.translationsOpt = parser.Str(name("translations"), "Read "+
+		"long descriptions from the given comma-separated Translation "+
+		"files, one per --"+name("packages")+" file, in the same order.",
+		"")
+	options.translationsOpt.SetShortName(clip.NoShortName)
+	options.translationsOpt.MustSetVarName("FILES")
+	options.mirrorOpt = parser.Str(name("mirror"), "Read the Packages "+
+		"(and Translation-en) files from the local Debian mirror in DIR "+
+		"(see --"+name("suites")+" and --"+name("components")+").", "")
+	options.mirrorOpt.MustSetVarName("DIR")
+	options.suitesOpt = parser.Str(name("suites"), "The comma-separated "+
+		"list of --"+name("mirror")+" suites to read [default: stable].",
+		"stable")
+	options.suitesOpt.SetShortName(clip.NoShortName)
+	options.componentsOpt = parser.Str(name("components"), "The "+
+		"comma-separated list of components, e.g., main,contrib: a "+
+		"--"+name("mirror")+"'s components to read; when searching, "+
+		"those to match [default: main for --"+name("mirror")+"; else "+
+		"match any component].", "")
+	options.componentsOpt.SetShortName(clip.NoShortName)
+	options.noVerifyOpt = parser.Flag(name("no-verify"), "Don't check "+
+		"--"+name("mirror")+" files against their suite's Release file "+
+		"checksums.")
+	options.noVerifyOpt.SetShortName(clip.NoShortName)
+	options.stdinOpt = parser.Flag(name("stdin"), "Read Packages data "+
+		"from stdin, e.g., from apt-cache dumpavail, instead of the apt "+
+		"lists.")
+	options.stdinOpt.SetShortName(clip.NoShortName)
+	options.debDirOpt = parser.Str(name("deb-dir"), "Also read the .deb "+
+		"files in DIR.", "")
+	options.debDirOpt.SetShortName(clip.NoShortName)
+	options.debDirOpt.MustSetVarName("DIR")
+	options.dpkgStatusOpt = parser.Flag(name("dpkg-status"), "Also read "+
+		"the installed packages from the dpkg status file (e.g., to "+
+		"include locally installed packages that aren't in any apt list).")
+	options.dpkgStatusOpt.SetShortName(clip.NoShortName)
+	if prefix != "" {
+		for _, option := range []interface{ SetShortName(rune) }{
+			options.arcOpt, options.rootOpt, options.packagesOpt,
+			options.mirrorOpt} {
+			option.SetShortName(clip.NoShortName)
+		}
+		options.arcOpt.MustSetVarName("ARC")
+		options.suitesOpt.MustSetVarName("SUITES")
+		options.componentsOpt.MustSetVarName("COMPONENTS")
+	}
+	return options
+}
+
+// hoisted returns the args with the input options (and their values)
+// moved to the front, so that they may also follow positionals, e.g.,
+// debsearch show NAME --packages FILES (the parser treats every argument
+// after the first positional as a positional).
+func (me *inputOptions) hoisted(args []string) []string {
+	valued := map[string]bool{}
+	flags := map[string]bool{}
+	for _, option := range []*clip.StrOption{me.arcOpt, me.rootOpt,
+		me.listsDirOpt, me.packagesOpt, me.translationsOpt, me.mirrorOpt,
+		me.suitesOpt, me.componentsOpt, me.debDirOpt} {
+		addOptionNames(valued, option.LongName(), option.ShortName())
+	}
+	for _, option := range []*clip.FlagOption{me.noVerifyOpt, me.stdinOpt,
+		me.dpkgStatusOpt} {
+		addOptionNames(flags, option.LongName(), option.ShortName())
+	}
+	inputs := make([]string, 0, len(args))
+	others := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, _, _ := strings.Cut(arg, "=")
+		switch {
+		case arg == "--":
+			others = append(others, args[i:]...)
+			i = len(args)
+		case flags[arg] || (valued[name] && name != arg):
+			inputs = append(inputs, arg)
+		case valued[arg] && i+1 < len(args):
+			inputs = append(inputs, arg, args[i+1])
+			i++
+		default:
+			others = append(others, arg)
+		}
+	}
+	return append(inputs, others...)
+}
+
+func addOptionNames(names map[string]bool, longName string, short rune) {
+	names["--"+longName] = true
+	if short != clip.NoShortName {
+		names["-"+string(short)] = true
+	}
+}
+
+// config must only be called after the parser has parsed.
+func (me *inputOptions) config(parser *clip.Parser) inputConfig {
+	config := inputConfig{arc: me.arcOpt.Value(), listsDir: ds.ListsPath,
+		statusFile: ds.StatusPath, infoDir: ds.InfoPath,
+		mirror:   me.mirrorOpt.Value(),
+		packages: commaSplit(me.packagesOpt.Value()),
+		verify:   !me.noVerifyOpt.Value(), stdin: me.stdinOpt.Value()}
+	config.translations = commaSplit(me.translationsOpt.Value())
+	config.debDir = me.debDirOpt.Value()
+	config.dpkgStatus = me.dpkgStatusOpt.Value()
+	config.suites = strings.Split(me.suitesOpt.Value(), ",")
+	if me.componentsOpt.Given() {
+		config.components = commaSplit(me.componentsOpt.Value())
+	}
+	if me.rootOpt.Given() && me.listsDirOpt.Given() {
+		parser.OnError(fmt.Errorf("can't use both --%s and --%s",
+			me.rootOpt.LongName(), me.listsDirOpt.LongName()))
+	}
+	if me.rootOpt.Given() {
+		config.listsDir = ds.ListsPathForRoot(me.rootOpt.Value())
+		config.statusFile = ds.StatusPathForRoot(me.rootOpt.Value())
+		config.infoDir = ds.InfoPathForRoot(me.rootOpt.Value())
+	} else if me.listsDirOpt.Given() {
+		config.listsDir = me.listsDirOpt.Value()
+	}
+	if len(config.translations) > len(config.packages) {
+		parser.OnError(fmt.Errorf("each --%s file needs a matching --%s "+
+			"file", me.translationsOpt.LongName(), me.packagesOpt.LongName()))
+	}
+	return config
+}
+
+type inputConfig struct {
+	arc          string
+	otherArcs    []string // also read, e.g., for lock's NAME:ARC names
+	listsDir     string
+	statusFile   string
+	infoDir      string // dpkg's, for installed packages' shlibs
+	packages     []string
+	translations []string
+	mirror       string
+	suites       []string
+	components   []string // if empty, a --mirror's main and any others
+	verify       bool
+	stdin        bool
+	debDir       string
+	dpkgStatus   bool
+	verbose      bool    // report per-source timings on stderr
+	warnings     []error // failures that still allow a (partial) model
+}
+
+func (me *inputConfig) filePairs(withDescriptions bool) []ds.FilePair {
+	if me.stdin {
+		return []ds.FilePair{ds.NewReaderPair("<stdin>", os.Stdin, nil)}
+	}
+	if len(me.packages) > 0 {
+		pairs := make([]ds.FilePair, 0, len(me.packages))
+		for i, packages := range me.packages {
+			i18n := ""
+			if i < len(me.translations) {
+				i18n = me.translations[i]
+			}
+			pairs = append(pairs, ds.NewFilePair(packages, i18n))
+		}
+		return pairs
+	}
+	var pairs []ds.FilePair
+	for _, arc := range append([]string{me.arc}, me.otherArcs...) {
+		pairs = append(pairs, me.arcFilePairs(arc, withDescriptions)...)
+	}
+	return pairs
+}
+
+// arcFilePairs returns the --mirror's or apt lists' files for the arc.
+func (me *inputConfig) arcFilePairs(arc string,
+	withDescriptions bool) []ds.FilePair {
+	if me.mirror != "" {
+		components := me.components
+		if len(components) == 0 {
+			components = []string{ds.MainComponent}
+		}
+		mirror := ds.NewMirror(me.mirror, arc, me.suites, components)
+		mirror.Verify = me.verify
+		pairs, err := mirror.FilePairs(withDescriptions)
+		if err != nil {
+			me.warnings = append(me.warnings, err)
+		}
+		return pairs
+	}
+	if withDescriptions {
+		return ds.StdFilePairsWithDescriptionsIn(me.listsDir, arc)
+	}
+	return ds.StdFilePairsIn(me.listsDir, arc)
+}
+
+// addOtherArc adds the arc to those read (unless it is already read or
+// isn't a real arc, e.g., "all").
+func (me *inputConfig) addOtherArc(arc string) {
+	if arc != "" && arc != me.arc && arc != "all" && arc != "any" &&
+		arc != "native" && !slices.Contains(me.otherArcs, arc) {
+		me.otherArcs = append(me.otherArcs, arc)
+	}
+}
+
+func (me *inputConfig) sources(withDescriptions bool) []ds.Source {
+	sources := []ds.Source{}
+	for _, pair := range me.filePairs(withDescriptions) {
+		sources = append(sources, pair)
+	}
+	if me.debDir != "" {
+		sources = append(sources, ds.NewDebDir(me.debDir))
+	}
+	if me.dpkgStatus {
+		sources = append(sources, ds.NewDpkgStatus(me.statusFile))
+	}
+	return sources
+}
+
+func (me *inputConfig) readModel(withDescriptions bool) ds.Model {
+	options := ds.LoadOptions{}
+	if me.verbose {
+		options.Progress = func(progress ds.Progress) {
+			fmt.Fprintf(os.Stderr, "read %s in %s [%d/%d] %s bytes "+
+				"%s pkgs\n", progress.Origin, progress.Elapsed,
+				progress.SourcesDone, progress.SourcesTotal,
+				gong.Commas(int(progress.BytesRead)),
+				gong.Commas(progress.PackagesParsed))
+		}
+	}
+	model, err := ds.NewModelContext(context.Background(), options,
+		me.sources(withDescriptions)...)
+	gong.CheckError("failed to read package files: ", err)
+	for _, warning := range model.Warnings {
+		me.warnings = append(me.warnings, warning)
+	}
+	printWarnings(me.warnings)
+	return model
+}
+
+// printWarnings prints each warning (indenting any continuation lines,
+// e.g., from joined errors) to stderr.
+func printWarnings(warnings []error) {
+	if len(warnings) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "warnings (%d):\n", len(warnings))
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "  %s\n", strings.ReplaceAll(
+			warning.Error(), "\n", "\n    "))
+	}
+}
+
+func (me *inputConfig) String() string {
+	return fmt.Sprintf("arc=%s otherArcs=%q listsDir=%q statusFile=%q "+
+		"packages=%q translations=%q mirror=%q suites=%q components=%q "+
+		"verify=%t stdin=%t debDir=%q dpkgStatus=%t", me.arc,
+		me.otherArcs, me.listsDir, me.statusFile, me.packages,
+		me.translations, me.mirror, me.suites, me.components, me.verify,
+		me.stdin, me.debDir, me.dpkgStatus)
+}
@@ -0,0 +1,123 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mark-summerfield/clip"
+	ds "github.com/mark-summerfield/debsearch"
+	"github.com/mark-summerfield/gong"
+)
+
+const (
+	listSections = "sections"
+	listTags     = "tags"
+	listFacets   = "facets"
+	listComps    = "components"
+	listKinds    = "kinds"
+	listArcs     = "arcs"
+)
+
+func listMain(args []string) {
+	parser := newCommandParser("list", "Print the section names, tag "+
+		"names, debtags facets, component names, package kinds, or "+
+		"arc(hitecture) names.")
+	inputOpts := newInputOptions(&parser)
+	verboseOpt := parser.Flag("verbose", "Print a heading and how many "+
+		"packages are in each section, component, or kind or have each "+
+		"tag (for facets, print each facet's tags and their descriptions).")
+	parser.PositionalCount = clip.OnePositional
+	parser.PositionalHelp = "What to list: sections, tags, facets, " +
+		"components, kinds, or arcs."
+	parser.MustSetPositionalVarName("WHAT")
+	if err := parser.ParseArgs(inputOpts.hoisted(args)); err != nil {
+		parser.OnError(err) // doesn't return
+	}
+	input := inputOpts.config(&parser)
+	switch what := parser.Positionals[0]; what {
+	case listArcs:
+		printArcs(verboseOpt.Value())
+	case listSections:
+		model := input.readModel(false)
+		printNamesAndCounts("Sections", model.SectionsAndCounts,
+			verboseOpt.Value())
+	case listTags:
+		model := input.readModel(false)
+		printNamesAndCounts("Tags", model.TagsAndCounts, verboseOpt.Value())
+	case listComps:
+		model := input.readModel(false)
+		printNamesAndCounts("Components", model.ComponentsAndCounts,
+			verboseOpt.Value())
+	case listKinds:
+		model := input.readModel(false)
+		printNamesAndCounts("Kinds", model.KindsAndCounts,
+			verboseOpt.Value())
+	case listFacets:
+		model := input.readModel(false)
+		printFacets(&model, verboseOpt.Value())
+	default:
+		parser.OnError(fmt.Errorf("can't list %q: expected %s, %s, %s, "+
+			"%s, %s, or %s", what, listSections, listTags, listFacets,
+			listComps, listKinds, listArcs))
+	}
+}
+
+func printArcs(verbose bool) {
+	arcs := strings.Fields(ds.Arcs)
+	if verbose {
+		fmt.Printf("Arcs (%d):\n", len(arcs))
+	}
+	for _, arc := range arcs {
+		if verbose && arc == ds.DefaultArc {
+			arc += " [default]"
+		}
+		fmt.Println(arc)
+	}
+}
+
+// printFacets prints the model's facets with their descriptions from the
+// debtags vocabulary and, if verbose, their tags.
+func printFacets(model *ds.Model, verbose bool) {
+	vocabulary := ds.DefaultVocabulary()
+	tagsForFacet := model.TagsByFacet()
+	if verbose {
+		fmt.Printf("Facets (%d):\n", len(tagsForFacet))
+	}
+	for _, facet := range gong.SortedMapKeys(tagsForFacet) {
+		printDescribed(facet, vocabulary.Describe(facet))
+		if verbose {
+			for _, tag := range tagsForFacet[facet] {
+				_, name := ds.SplitTag(tag)
+				printDescribed(fmt.Sprintf("  %s (%s)", name,
+					gong.Commas(model.TagsAndCounts[tag])),
+					vocabulary.Describe(tag))
+			}
+		}
+	}
+}
+
+func printDescribed(name, desc string) {
+	if desc == "" {
+		fmt.Println(name)
+	} else {
+		fmt.Printf("%s — %s\n", name, desc)
+	}
+}
+
+func printNamesAndCounts(title string, namesAndCounts map[string]int,
+	withCounts bool) {
+	if withCounts {
+		fmt.Printf("%s (%d):\n", title, len(namesAndCounts))
+	}
+	for _, name := range gong.SortedMapKeys(namesAndCounts) {
+		if withCounts {
+			fmt.Printf("%s (%s)\n", name,
+				gong.Commas(namesAndCounts[name]))
+		} else {
+			fmt.Println(name)
+		}
+	}
+}
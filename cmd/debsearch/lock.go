@@ -0,0 +1,143 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/mark-summerfield/clip"
+	ds "github.com/mark-summerfield/debsearch"
+	"github.com/mark-summerfield/gong"
+)
+
+const (
+	lockJson   = "json"
+	lockDeb822 = "deb822"
+)
+
+func lockMain(args []string) {
+	parser := newCommandParser("lock", "Write a lockfile of the named "+
+		"packages' exact versions, architectures, filenames, and "+
+		"SHA256s (and optionally those of their dependencies), e.g., "+
+		"to pin a Docker image's packages; or, with --verify, report "+
+		"how a lockfile's packages have drifted from the current "+
+		"packages. Exits with 1 if any package can't be resolved or "+
+		"has drifted.")
+	inputOpts := newInputOptions(&parser)
+	closureOpt := parser.Flag("closure", "Also lock the packages' "+
+		"Pre-Depends and Depends (recursively).")
+	closureOpt.SetShortName(clip.NoShortName)
+	relationOpts := newRelationOptions(&parser)
+	formatOpt := parser.Choice("format", "The lockfile format [default: "+
+		lockDeb822+"].", []string{lockDeb822, lockJson}, lockDeb822)
+	formatOpt.SetShortName(clip.NoShortName)
+	outputOpt := parser.Str("output", "Write the lockfile to FILE "+
+		"[default: stdout].", "")
+	outputOpt.SetShortName(clip.NoShortName)
+	outputOpt.MustSetVarName("FILE")
+	verifyOpt := parser.Str("verify", "Report the packages in the "+
+		"lockfile FILE (JSON or deb822) that are no longer available "+
+		"or whose version or .deb has changed.", "")
+	verifyOpt.SetShortName(clip.NoShortName)
+	verifyOpt.MustSetVarName("FILE")
+	parser.PositionalCount = clip.ZeroOrMorePositionals
+	parser.PositionalHelp = "The packages to lock (for --arc unless " +
+		"qualified with another, e.g., libc6:i386, whose apt lists or " +
+		"--mirror files are then read too; --verify likewise reads " +
+		"those of every arc in the lockfile)."
+	parser.MustSetPositionalVarName("NAME")
+	if err := parser.ParseArgs(inputOpts.hoisted(args)); err != nil {
+		parser.OnError(err) // doesn't return
+	}
+	if verifyOpt.Given() == (len(parser.Positionals) > 0) {
+		parser.OnError(errors.New(
+			"expected either package names or --verify FILE"))
+	}
+	input := inputOpts.config(&parser)
+	if verifyOpt.Given() {
+		lockfile, err := ds.ReadLockfile(verifyOpt.Value())
+		gong.CheckError("", err)
+		for _, entry := range lockfile.Packages {
+			input.addOtherArc(entry.Arch)
+		}
+		model := input.readModel(false)
+		if !printDrifts(model.VerifyLock(lockfile)) {
+			os.Exit(1)
+		}
+		return
+	}
+	for _, name := range parser.Positionals {
+		_, arc, _ := strings.Cut(name, ":")
+		input.addOtherArc(arc)
+	}
+	model := input.readModel(false)
+	var kinds []ds.RelationKind
+	if closureOpt.Value() {
+		kinds = relationOpts.kinds()
+	}
+	lockfile, unresolved := model.Lock(input.arc, parser.Positionals,
+		kinds...)
+	writeLockfile(&lockfile, formatOpt.Value(), outputOpt.Value())
+	for _, name := range unresolved {
+		why := ""
+		if _, arc, found := strings.Cut(name, ":"); found &&
+			slices.Contains(parser.Positionals, name) && // not a relation
+			!model.HasArc(arc) {
+			why = " (no " + arc + " packages were read)"
+		}
+		fmt.Fprintf(os.Stderr, "can't resolve: %s%s\n", name, why)
+	}
+	if len(unresolved) > 0 {
+		os.Exit(1)
+	}
+}
+
+func writeLockfile(lockfile *ds.Lockfile, format, filename string) {
+	file := os.Stdout
+	if filename != "" {
+		var err error
+		file, err = os.Create(filename)
+		gong.CheckError("failed to create lockfile: ", err)
+	}
+	var err error
+	if format == lockJson {
+		err = lockfile.WriteJson(file)
+	} else {
+		err = lockfile.WriteDeb822(file)
+	}
+	if file != os.Stdout {
+		err = errors.Join(err, file.Close())
+	}
+	gong.CheckError("failed to write lockfile: ", err)
+}
+
+// printDrifts prints each drifted package and returns true if there are
+// none.
+func printDrifts(drifts []ds.LockDrift) bool {
+	for _, drift := range drifts {
+		entry := drift.Entry
+		switch deb := drift.Deb; {
+		case drift.ArcMissing:
+			fmt.Printf("%s v%s: not checked (no %s packages were read)\n",
+				entry.Name, entry.Version, entry.Arch)
+		case deb == nil:
+			fmt.Printf("%s v%s: no longer available\n", entry.Name,
+				entry.Version)
+		case deb.Version != entry.Version:
+			fmt.Printf("%s v%s: now v%s\n", entry.Name, entry.Version,
+				deb.Version)
+		case deb.Arch != entry.Arch:
+			fmt.Printf("%s v%s: now for %s (was %s)\n", entry.Name,
+				entry.Version, deb.Arch, entry.Arch)
+		default:
+			fmt.Printf("%s v%s: .deb changed (SHA256 %s, was %s)\n",
+				entry.Name, entry.Version, deb.Sha256, entry.Sha256)
+		}
+	}
+	return len(drifts) == 0
+}
@@ -0,0 +1,129 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mark-summerfield/clip"
+	ds "github.com/mark-summerfield/debsearch"
+	"github.com/mark-summerfield/gong"
+)
+
+func neededMain(args []string) {
+	parser := newCommandParser("needed", "Print the packages that "+
+		"provide the shared libraries that the ELF binary or shared "+
+		"object FILE needs, noting those already installed. The packages "+
+		"read are those for FILE's arc (which --arc, if given, must "+
+		"match). Exits 1 if any library is neither installed nor "+
+		"available.")
+	inputOpts := newInputOptions(&parser)
+	contentsOpt := newContentsOption(&parser)
+	parser.PositionalCount = clip.OnePositional
+	parser.MustSetPositionalVarName("FILE")
+	if err := parser.ParseArgs(inputOpts.hoisted(args)); err != nil {
+		parser.OnError(err) // doesn't return
+	}
+	filename := parser.Positionals[0]
+	input := inputOpts.config(&parser)
+	needs := readNeeds(&parser, inputOpts, &input, filename)
+	model := input.readModel(false)
+	checkNeededArc(&model, filename, needs)
+	if !printNeeded(&model, filename, needs, input.infoDir,
+		commaSplit(contentsOpt.Value())) {
+		os.Exit(1)
+	}
+}
+
+// newContentsOption returns the --contents option used with FILE (or
+// search's --needed-by FILE).
+func newContentsOption(parser *clip.Parser) *clip.StrOption {
+	contentsOpt := parser.Str("contents", "Use the comma-separated "+
+		"list of Contents indexes (e.g., a mirror's "+
+		"dists/stable/main/Contents-amd64.gz) to find the libraries "+
+		"[default: guess packages from the libraries' names].", "")
+	contentsOpt.SetShortName(clip.NoShortName)
+	contentsOpt.MustSetVarName("FILES")
+	return contentsOpt
+}
+
+// readNeeds returns what the ELF file needs and sets the input's arc to
+// the file's so that the packages read are for it; if --arc was given it
+// must match.
+func readNeeds(parser *clip.Parser, inputOpts *inputOptions,
+	input *inputConfig, filename string) ds.ElfNeeds {
+	needs, err := ds.ReadElfNeeds(filename)
+	gong.CheckError("", err)
+	if needs.Arc != "" && needs.Arc != input.arc {
+		if inputOpts.arcOpt.Given() {
+			parser.OnError(fmt.Errorf("%s is for %s, not --arc %s",
+				filename, needs.Arc, input.arc)) // doesn't return
+		}
+		input.arc = needs.Arc // read the apt lists (or mirror) for it
+	}
+	return needs
+}
+
+// checkNeededArc exits with 1 if none of the model's packages are for the
+// ELF file's arc, e.g., because its apt lists haven't been fetched.
+func checkNeededArc(model *ds.Model, filename string, needs ds.ElfNeeds) {
+	if needs.Arc != "" && !model.HasArc(needs.Arc) {
+		fmt.Fprintf(os.Stderr, "none of the packages read are for %s "+
+			"(%s's arc)\n", needs.Arc, filename)
+		os.Exit(1)
+	}
+}
+
+// printNeeded prints each shared library that the ELF file needs with the
+// packages that provide it and returns false if any library is neither
+// installed nor available.
+func printNeeded(model *ds.Model, filename string, needs ds.ElfNeeds,
+	infoDir string, contentsFiles []string) bool {
+	installed, err := ds.ReadShlibs(infoDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err) // can still use the model
+	}
+	contents := ds.SonameIndex{}
+	for _, contentsFile := range contentsFiles {
+		index, err := ds.ReadContents(contentsFile, needs.Sonames)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+		for soname, names := range index {
+			contents[soname] = append(contents[soname], names...)
+		}
+	}
+	arc := needs.Arc
+	if arc == "" {
+		arc = "unknown arc"
+	}
+	fmt.Printf("%s (%s) needs:\n", filename, arc)
+	if needs.Arc != "" && needs.Arc != ds.DefaultArc {
+		fmt.Printf("  (install the %s packages with, e.g., NAME:%s)\n",
+			needs.Arc, needs.Arc)
+	}
+	ok := true
+	for _, library := range model.NeededLibraries(needs, installed,
+		contents) {
+		switch {
+		case len(library.Installed) > 0:
+			fmt.Printf("  %s: installed (%s)\n", library.Soname,
+				strings.Join(library.Installed, ", "))
+		case len(library.Debs) > 0:
+			names := make([]string, 0, len(library.Debs))
+			for _, deb := range library.Debs {
+				names = append(names, deb.Name+" v"+deb.Version)
+			}
+			fmt.Printf("  %s: %s\n", library.Soname,
+				strings.Join(names, " | "))
+		default:
+			fmt.Printf("  %s: not found\n", library.Soname)
+			ok = false
+		}
+	}
+	return ok
+}
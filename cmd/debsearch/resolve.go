@@ -0,0 +1,126 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mark-summerfield/clip"
+	ds "github.com/mark-summerfield/debsearch"
+)
+
+var ecosystemHelp = map[string]string{
+	"pypi":  "PyPI names, e.g., requests",
+	"npm":   "npm names, e.g., lodash or @babel/core",
+	"cpan":  "CPAN modules or distributions, e.g., JSON::XS",
+	"go":    "Go import paths, e.g., github.com/spf13/cobra",
+	"crate": "Rust crates, e.g., serde",
+}
+
+// moduleOptions are the options that name modules to resolve, shared
+// by the resolve command and search (where they're aliases of it).
+type moduleOptions struct {
+	ecosystemOpts []*clip.StrOption // indexed by ds.Ecosystem
+	moduleOpt     *clip.StrOption
+	manifestOpt   *clip.StrOption
+}
+
+func newModuleOptions(parser *clip.Parser) *moduleOptions {
+	options := &moduleOptions{ecosystemOpts: make([]*clip.StrOption, 0,
+		len(ds.EcosystemNames()))}
+	for _, name := range ds.EcosystemNames() {
+		ecosystemOpt := parser.Str(name, "Print the packages that "+
+			"provide the comma-separated list of "+ecosystemHelp[name]+
+			".", "")
+		ecosystemOpt.SetShortName(clip.NoShortName)
+		ecosystemOpt.MustSetVarName("NAMES")
+		options.ecosystemOpts = append(options.ecosystemOpts,
+			ecosystemOpt)
+	}
+	options.moduleOpt = parser.Str("module", "Print the packages that "+
+		"provide the comma-separated list of ECOSYSTEM:NAME modules, "+
+		"e.g., pypi:requests,crate:serde; ECOSYSTEM is one of: "+
+		strings.Join(ds.EcosystemNames(), ", ")+".", "")
+	options.moduleOpt.SetShortName(clip.NoShortName)
+	options.moduleOpt.MustSetVarName("MODULES")
+	options.manifestOpt = parser.Str("manifest", "Print the packages "+
+		"that provide the modules required by the comma-separated list "+
+		"of requirements.txt, package.json, cpanfile, go.mod, or "+
+		"Cargo.toml files.", "")
+	options.manifestOpt.SetShortName(clip.NoShortName)
+	options.manifestOpt.MustSetVarName("FILES")
+	return options
+}
+
+// modules returns the modules named by the options in the order given.
+func (me *moduleOptions) modules(parser *clip.Parser) []ds.Module {
+	var modules []ds.Module
+	for i, ecosystemOpt := range me.ecosystemOpts {
+		for _, name := range commaSplit(ecosystemOpt.Value()) {
+			modules = append(modules,
+				ds.Module{Ecosystem: ds.Ecosystem(i), Name: name})
+		}
+	}
+	for _, text := range commaSplit(me.moduleOpt.Value()) {
+		module, err := ds.ParseModule(text)
+		if err != nil {
+			parser.OnError(err) // doesn't return
+		}
+		modules = append(modules, module)
+	}
+	for _, filename := range commaSplit(me.manifestOpt.Value()) {
+		manifestModules, err := ds.ReadManifest(filename)
+		if err != nil {
+			parser.OnError(err) // doesn't return
+		}
+		modules = append(modules, manifestModules...)
+	}
+	return modules
+}
+
+func resolveMain(args []string) {
+	parser := newCommandParser("resolve", "Print the packages that "+
+		"provide the given language ecosystem modules (the most likely "+
+		"first), e.g., to find the Debian packages for a project's "+
+		"dependencies. Exits 1 if any module has no package.")
+	inputOpts := newInputOptions(&parser)
+	moduleOpts := newModuleOptions(&parser)
+	if err := parser.ParseArgs(inputOpts.hoisted(args)); err != nil {
+		parser.OnError(err) // doesn't return
+	}
+	modules := moduleOpts.modules(&parser)
+	if len(modules) == 0 {
+		parser.OnError(errors.New("expected at least one module, e.g., " +
+			"--pypi NAMES or --manifest FILES"))
+	}
+	input := inputOpts.config(&parser)
+	model := input.readModel(false)
+	if !printResolutions(&model, modules) {
+		os.Exit(1)
+	}
+}
+
+// printResolutions prints the packages that provide each module (the
+// most likely first) and returns false if any module has none.
+func printResolutions(model *ds.Model, modules []ds.Module) bool {
+	resolver := ds.NewResolver(model)
+	ok := true
+	for _, module := range modules {
+		debs := resolver.Resolve(module)
+		if len(debs) == 0 {
+			fmt.Printf("%s: not found\n", module)
+			ok = false
+			continue
+		}
+		names := make([]string, 0, len(debs))
+		for _, deb := range debs {
+			names = append(names, deb.Name+" v"+deb.Version)
+		}
+		fmt.Printf("%s: %s\n", module, strings.Join(names, " | "))
+	}
+	return ok
+}
@@ -0,0 +1,295 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mark-summerfield/clip"
+	ds "github.com/mark-summerfield/debsearch"
+	"github.com/mark-summerfield/gong"
+	"github.com/mark-summerfield/gset"
+)
+
+func searchMain(args []string) {
+	config := getSearchConfig(args)
+	t := time.Now()
+	model := config.input.readModel(!config.query.Words.IsEmpty())
+	if config.listArcs { // legacy flat options
+		printArcs(config.verbose)
+	}
+	if config.listSections {
+		printNamesAndCounts("Sections", model.SectionsAndCounts,
+			config.verbose)
+	}
+	if config.listTags {
+		printNamesAndCounts("Tags", model.TagsAndCounts, config.verbose)
+	}
+	elapsed := time.Since(t)
+	ok := len(config.modules) == 0 ||
+		printResolutions(&model, config.modules) // as resolve does
+	if config.neededBy != "" { // as needed does
+		checkNeededArc(&model, config.neededBy, config.needs)
+		ok = printNeeded(&model, config.neededBy, config.needs,
+			config.input.infoDir, config.contents) && ok
+	}
+	if config.IsSearch() {
+		search(config, model, elapsed)
+	} else if config.verbose {
+		fmt.Printf("searched %s pkgs in %s.\n",
+			gong.Commas(len(model.Debs)), elapsed)
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+func search(config *SearchConfig, model ds.Model, elapsed time.Duration) {
+	result := config.query.Search(&model)
+	matches := result.Debs
+	if result.Total == 0 {
+		fmt.Printf(
+			"searched %s pkgs in %s; no matching packages found.\n",
+			gong.Commas(len(model.Debs)), elapsed)
+	} else {
+		for _, deb := range matches {
+			fmt.Printf("* %s\n", deb)
+			for _, snippet := range result.Snippets[deb.Name] {
+				fmt.Printf("    %s\n", highlightSnippet(snippet))
+			}
+			if config.explain {
+				printExplanation(config.query.Explain(deb))
+			}
+		}
+		if len(matches) == 0 {
+			fmt.Printf("(--offset %s skips all %s matches)\n",
+				gong.Commas(config.query.Offset), gong.Commas(result.Total))
+		} else if len(matches) < result.Total {
+			fmt.Printf("(%s–%s of %s matches)\n",
+				gong.Commas(config.query.Offset+1),
+				gong.Commas(config.query.Offset+len(matches)),
+				gong.Commas(result.Total))
+		}
+		if config.facets {
+			printNamesAndCounts("Sections", result.SectionsAndCounts, true)
+			printNamesAndCounts("Tags", result.TagsAndCounts, true)
+			printNamesAndCounts("Components", result.ComponentsAndCounts,
+				true)
+			printNamesAndCounts("Kinds", result.KindsAndCounts, true)
+		}
+		if config.verbose {
+			fmt.Printf("found %s/%s pkgs in %s\n",
+				gong.Commas(result.Total), gong.Commas(len(model.Debs)),
+				elapsed)
+		}
+	}
+}
+
+// highlightSnippet returns the snippet with its matched words in bold if
+// stdout is a terminal.
+func highlightSnippet(snippet ds.Snippet) string {
+	if gong.IsTTY() {
+		return snippet.Highlighted(ansiBold, ansiReset, nil)
+	}
+	return snippet.Text
+}
+
+func printExplanation(explanation ds.Explanation) {
+	if explanation.Component != "" {
+		fmt.Printf("    component: %s\n", explanation.Component)
+	}
+	if explanation.Section != "" {
+		fmt.Printf("    section: %s\n", explanation.Section)
+	}
+	if explanation.Kind != "" {
+		fmt.Printf("    kind: %s\n", explanation.Kind)
+	}
+	if len(explanation.Tags) > 0 {
+		fmt.Printf("    tags: %s\n", strings.Join(explanation.Tags, ", "))
+	}
+	for _, field := range []string{ds.NameField, ds.ShortDescField,
+		ds.LongDescField} {
+		if matches := explanation.MatchesIn(field); len(matches) > 0 {
+			words := make([]string, 0, len(matches))
+			for _, match := range matches {
+				words = append(words, fmt.Sprintf("%s@%d-%d", match.Word,
+					match.Start, match.End))
+			}
+			fmt.Printf("    %s: %s\n", field, strings.Join(words, " "))
+		}
+	}
+}
+
+func getSearchConfig(args []string) *SearchConfig {
+	parser := newCommandParser("search", "Search for Debian packages "+
+		"by section, tags, and words.")
+	parser.SetAppName("debsearch [search]")
+	debugOpt := parser.Flag("debug", "")
+	debugOpt.Hide()
+	inputOpts := newInputOptions(&parser)
+	sectionsOpt := parser.Str("sections", "Match any of the "+
+		"comma-separated list of sections [default: match any section].",
+		"")
+	tagsOpt := parser.Str("tags", "Match the comma-separated list "+
+		"of tags; use FACET/* for any of a facet's tags, e.g., "+
+		"implemented-in/* [default: match any tags].", "")
+	allTagsOpt := parser.Flag("all-tags", "Match all the "+
+		"given tags [default: match any given tag].")
+	allTagsOpt.SetShortName(clip.NoShortName)
+	kindsOpt := parser.Str("kinds", "Match any of the comma-separated "+
+		"list of package kinds: "+strings.Join(ds.KindNames(), ", ")+
+		" [default: match any kind].", "")
+	kindsOpt.SetShortName(clip.NoShortName)
+	notKindsOpt := parser.Str("exclude-kinds", "Don't match any of the "+
+		"comma-separated list of package kinds, e.g., "+
+		"debug,documentation,transitional [default: exclude none].", "")
+	notKindsOpt.SetShortName(clip.NoShortName)
+	allWordsOpt := parser.Flag("all-words", "Match all the "+
+		"given words [default: match any given word].")
+	allWordsOpt.SetShortName(clip.NoShortName)
+	sortOpt := parser.Choice("sort", "Sort the matches by KEY; "+
+		"relevance is to the given words [default: name].",
+		ds.SortKeyNames(), ds.SortByName.String())
+	sortOpt.SetShortName(clip.NoShortName)
+	sortOpt.MustSetVarName("KEY")
+	reverseOpt := parser.Flag("reverse", "Sort in descending order.")
+	reverseOpt.SetShortName(clip.NoShortName)
+	limitOpt := parser.Int("limit", "Print at most N matches "+
+		"[default: print them all].", 0)
+	limitOpt.SetShortName(clip.NoShortName)
+	limitOpt.MustSetVarName("N")
+	offsetOpt := parser.Int("offset", "Skip the first N (sorted) "+
+		"matches, e.g., to page through them with --limit [default: 0].",
+		0)
+	offsetOpt.SetShortName(clip.NoShortName)
+	offsetOpt.MustSetVarName("N")
+	explainOpt := parser.Flag("explain", "Follow each match with which "+
+		"of the given components, sections, kinds, tags, and words it "+
+		"has; words are shown as WORD@START-END byte offsets in each "+
+		"field.")
+	facetsOpt := parser.Flag("facets", "Follow the matches with how "+
+		"many of them are in each section, have each tag, are in each "+
+		"component, and are of each kind.")
+	listArcsOpt := parser.Flag("list-arcs", "") // use: debsearch list
+	listArcsOpt.SetShortName(clip.NoShortName)
+	listArcsOpt.Hide()
+	listTagsOpt := parser.Flag("list-tags", "")
+	listTagsOpt.SetShortName(clip.NoShortName)
+	listTagsOpt.Hide()
+	listSectionsOpt := parser.Flag("list-sections", "")
+	listSectionsOpt.SetShortName(clip.NoShortName)
+	listSectionsOpt.Hide()
+	moduleOpts := newModuleOptions(&parser) // use: debsearch resolve
+	neededByOpt := parser.Str("needed-by", "Print the packages that "+
+		"provide the shared libraries that the ELF binary or shared "+
+		"object FILE needs, as the needed command does.", "")
+	neededByOpt.SetShortName(clip.NoShortName)
+	neededByOpt.MustSetVarName("FILE")
+	contentsOpt := newContentsOption(&parser)
+	verboseOpt := parser.Flag("verbose",
+		"Print number of packages and how long to read them (with "+
+			"per-file timings on stderr).")
+	parser.PositionalCount = clip.ZeroOrMorePositionals
+	parser.PositionalHelp = "Match the given (case-folded) words in " +
+		"descriptions [no default]."
+	parser.MustSetPositionalVarName("WORD")
+	if err := parser.ParseArgs(inputOpts.hoisted(args)); err != nil {
+		parser.OnError(err) // doesn't return
+		return nil          // never reached
+	}
+	config := SearchConfig{input: inputOpts.config(&parser),
+		query: ds.NewQuery(), listArcs: listArcsOpt.Value(),
+		listTags: listTagsOpt.Value(), listSections: listSectionsOpt.Value(),
+		facets: facetsOpt.Value(), explain: explainOpt.Value(),
+		verbose: verboseOpt.Value()}
+	config.input.verbose = config.verbose
+	config.query.Components.Add(config.input.components...)
+	if sectionsOpt.Given() {
+		config.query.AddSections(commaSplit(sectionsOpt.Value())...)
+	}
+	if tagsOpt.Given() {
+		config.query.Tags.Add(commaSplit(tagsOpt.Value())...)
+	}
+	if err := addKinds(config.query.Kinds, kindsOpt.Value()); err != nil {
+		parser.OnError(err) // doesn't return
+	}
+	if err := addKinds(config.query.NotKinds,
+		notKindsOpt.Value()); err != nil {
+		parser.OnError(err) // doesn't return
+	}
+	config.query.SortBy, _ = ds.ParseSortKey(sortOpt.Value()) // a Choice
+	config.query.Reverse = reverseOpt.Value()
+	config.query.Limit = max(0, limitOpt.Value())
+	config.query.Offset = max(0, offsetOpt.Value())
+	config.query.TagsAnd = allTagsOpt.Value()
+	config.query.WordsAnd = allWordsOpt.Value()
+	config.modules = moduleOpts.modules(&parser)
+	if config.neededBy = neededByOpt.Value(); config.neededBy != "" {
+		config.needs = readNeeds(&parser, inputOpts, &config.input,
+			config.neededBy)
+		config.contents = commaSplit(contentsOpt.Value())
+	}
+	if len(parser.Positionals) > 0 {
+		for _, word := range parser.Positionals {
+			config.query.Words.Add(strings.ToLower(word))
+		}
+	}
+	if !config.IsValid() {
+		parser.OnHelp() // doesn't return
+	}
+	if debugOpt.Value() {
+		fmt.Println(config.query)
+	}
+	return &config
+}
+
+type SearchConfig struct {
+	input        inputConfig
+	query        *ds.Query
+	modules      []ds.Module // to resolve to packages
+	neededBy     string      // an ELF file whose libraries to find
+	needs        ds.ElfNeeds // what neededBy needs
+	contents     []string    // Contents indexes for neededBy
+	listArcs     bool
+	listTags     bool
+	listSections bool
+	facets       bool
+	explain      bool
+	verbose      bool
+}
+
+func (me *SearchConfig) IsValid() bool {
+	return me.listArcs || me.listTags || me.listSections ||
+		len(me.modules) > 0 || me.neededBy != "" || me.IsSearch()
+}
+
+func (me *SearchConfig) IsSearch() bool {
+	return !me.query.Components.IsEmpty() ||
+		!me.query.Sections.IsEmpty() || !me.query.Tags.IsEmpty() ||
+		!me.query.Kinds.IsEmpty() || !me.query.NotKinds.IsEmpty() ||
+		!me.query.Words.IsEmpty()
+}
+
+// addKinds adds the comma-separated kinds to the set.
+func addKinds(kinds gset.Set[ds.Kind], names string) error {
+	for _, name := range commaSplit(names) {
+		kind, err := ds.ParseKind(name)
+		if err != nil {
+			return err
+		}
+		kinds.Add(kind)
+	}
+	return nil
+}
+
+func (me *SearchConfig) String() string {
+	return fmt.Sprintf("%s query=%s modules=%v neededBy=%q contents=%q "+
+		"listArcs=%t listTags=%t listSections=%t facets=%t explain=%t "+
+		"verbose=%t", &me.input, me.query, me.modules, me.neededBy,
+		me.contents, me.listArcs, me.listTags, me.listSections,
+		me.facets, me.explain, me.verbose)
+}
@@ -0,0 +1,247 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package main
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"strings"
+
+	"github.com/mark-summerfield/clip"
+	ds "github.com/mark-summerfield/debsearch"
+	"github.com/mark-summerfield/gong"
+)
+
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+	formatText  = "text"
+	formatMd    = "markdown"
+	formatHtml  = "html"
+	ansiBold    = "\x1B[1m"
+	ansiGreen   = "\x1B[32m"
+	ansiYellow  = "\x1B[33m"
+	ansiReset   = "\x1B[0m"
+)
+
+func showMain(args []string) {
+	parser := newCommandParser("show", "Show every field of each named "+
+		"package including its long description, tags (grouped by "+
+		"facet), homepage, and whether it is installed, as text (with "+
+		"the long description reflowed), Markdown, or HTML.")
+	inputOpts := newInputOptions(&parser)
+	colorOpt := parser.Choice("color", "Whether to use ANSI colors "+
+		"[default: auto, i.e., only if stdout is a terminal].",
+		[]string{colorAuto, colorAlways, colorNever}, colorAuto)
+	colorOpt.SetShortName(clip.NoShortName)
+	widthOpt := parser.IntInRange("width", "Wrap long descriptions "+
+		"to the given width [default: the terminal's width].", 20, 500,
+		clip.GetWidth())
+	formatOpt := parser.Choice("format", "The output format [default: "+
+		"text].", []string{formatText, formatMd, formatHtml}, formatText)
+	formatOpt.SetShortName(clip.NoShortName)
+	parser.PositionalCount = clip.OneOrMorePositionals
+	parser.PositionalHelp = "The names of the packages to show."
+	parser.MustSetPositionalVarName("NAME")
+	if err := parser.ParseArgs(inputOpts.hoisted(args)); err != nil {
+		parser.OnError(err) // doesn't return
+	}
+	input := inputOpts.config(&parser)
+	model := input.readModel(true)
+	installed, _ := ds.InstalledVersions(input.statusFile)
+	printer := newShowPrinter(colorOpt.Value(), formatOpt.Value(),
+		widthOpt.Value(), installed)
+	if printer.format == formatHtml {
+		fmt.Println("<html><body>")
+	}
+	missing := 0
+	for i, name := range parser.Positionals {
+		if deb, ok := model.Debs[name]; ok {
+			if i > 0 {
+				fmt.Println()
+			}
+			printer.show(deb)
+		} else {
+			fmt.Fprintf(os.Stderr, "no package called %q\n", name)
+			missing++
+		}
+	}
+	if printer.format == formatHtml {
+		fmt.Println("</body></html>")
+	}
+	if missing > 0 {
+		os.Exit(1)
+	}
+}
+
+type showPrinter struct {
+	color     bool
+	format    string
+	width     int
+	installed map[string]string
+}
+
+// newShowPrinter returns a printer for the given format; colors are only
+// used for text.
+func newShowPrinter(color, format string, width int,
+	installed map[string]string) showPrinter {
+	return showPrinter{color: format == formatText &&
+		(color == colorAlways || (color == colorAuto && gong.IsTTY())),
+		format: format, width: width, installed: installed}
+}
+
+func (me showPrinter) show(deb *ds.Deb) {
+	switch me.format {
+	case formatMd:
+		fmt.Printf("## %s\n\n", ds.EscapeMarkdown(deb.Name))
+	case formatHtml:
+		fmt.Printf("<h2>%s</h2>\n<table>\n", html.EscapeString(deb.Name))
+	default:
+		me.field("Package", me.bold(deb.Name))
+	}
+	me.field("Version", deb.Version)
+	me.field("Installed", me.installedState(deb))
+	me.field("Architecture", deb.Arch)
+	me.field("Section", deb.QualifiedSection())
+	me.field("Priority", deb.Priority)
+	me.field("Source", deb.Source)
+	me.field("Maintainer", deb.Maintainer)
+	me.field("Installed-Size", ds.HumanSize(deb.Size))
+	if deb.DownloadSize > 0 {
+		me.field("Download-Size", ds.HumanBytes(deb.DownloadSize))
+	}
+	me.field("Provides", deb.Provides)
+	me.field("Pre-Depends", deb.PreDepends)
+	me.field("Depends", deb.Depends)
+	me.field("Recommends", deb.Recommends)
+	me.field("Suggests", deb.Suggests)
+	me.field("Conflicts", deb.Conflicts)
+	me.field("Breaks", deb.Breaks)
+	me.field("Replaces", deb.Replaces)
+	me.field("Filename", deb.Filename)
+	me.field("SHA256", deb.Sha256)
+	me.showHomepage(deb.Url)
+	me.showTags(deb)
+	me.showDescription(deb)
+}
+
+// field prints the field if it has a value; for Markdown and HTML the
+// value is escaped.
+func (me showPrinter) field(name, value string) {
+	if value == "" {
+		return
+	}
+	switch me.format {
+	case formatMd:
+		fmt.Printf("- **%s:** %s\n", name, ds.EscapeMarkdown(value))
+	case formatHtml:
+		fmt.Printf("<tr><th align=left>%s:</th><td>%s</td></tr>\n", name,
+			html.EscapeString(value))
+	default:
+		fmt.Printf("%s %s\n", me.bold(name+":"), value)
+	}
+}
+
+func (me showPrinter) showHomepage(url string) {
+	if url == "" {
+		return
+	}
+	switch me.format {
+	case formatMd:
+		fmt.Printf("- **Homepage:** <%s>\n", url)
+	case formatHtml:
+		url = html.EscapeString(url)
+		fmt.Printf("<tr><th align=left>Homepage:</th><td><a href=\"%s\">"+
+			"%s</a></td></tr>\n", url, url)
+	default:
+		me.field("Homepage", me.underline(url))
+	}
+}
+
+func (me showPrinter) showTags(deb *ds.Deb) {
+	if deb.Tags.IsEmpty() {
+		return
+	}
+	tagsForFacet := deb.TagsByFacet()
+	facets := gong.SortedMapKeys(tagsForFacet)
+	switch me.format {
+	case formatMd:
+		fmt.Println("- **Tags:**")
+		for _, facet := range facets {
+			fmt.Printf("  - %s: %s\n", ds.EscapeMarkdown(facet),
+				ds.EscapeMarkdown(strings.Join(tagsForFacet[facet], ", ")))
+		}
+	case formatHtml:
+		lines := make([]string, 0, len(facets))
+		for _, facet := range facets {
+			lines = append(lines, html.EscapeString(facet+": "+
+				strings.Join(tagsForFacet[facet], ", ")))
+		}
+		fmt.Printf("<tr><th align=left valign=top>Tags:</th><td>%s</td>"+
+			"</tr>\n", strings.Join(lines, "<br>"))
+	default:
+		fmt.Println(me.bold("Tags:"))
+		for _, facet := range facets {
+			fmt.Println(gong.WrappedX(strings.Join(tagsForFacet[facet],
+				", "), me.width, "  "+facet+": ", "    "))
+		}
+	}
+}
+
+// showDescription prints the short description and the long description
+// rendered in the printer's format.
+func (me showPrinter) showDescription(deb *ds.Deb) {
+	switch me.format {
+	case formatMd:
+		fmt.Printf("\n**%s**\n", ds.EscapeMarkdown(deb.ShortDesc))
+		if !deb.LongDesc.IsEmpty() {
+			fmt.Printf("\n%s\n", deb.LongDesc.Markdown())
+		}
+	case formatHtml:
+		fmt.Printf("</table>\n<p><b>%s</b></p>\n%s",
+			html.EscapeString(deb.ShortDesc), deb.LongDesc.Html(nil, "", ""))
+	default:
+		me.field("Description", deb.ShortDesc)
+		if !deb.LongDesc.IsEmpty() {
+			fmt.Println(deb.LongDesc.Text(me.width, "  "))
+		}
+	}
+}
+
+func (me showPrinter) installedState(deb *ds.Deb) string {
+	version, ok := me.installed[ds.InstalledKey(deb.Name, deb.Arch)]
+	if !ok {
+		return "no"
+	}
+	switch result := ds.CompareVersions(version, deb.Version); {
+	case result < 0:
+		return me.colored(ansiYellow, "v"+version+" (upgradable)")
+	case result > 0: // e.g., pinned or from backports
+		return me.colored(ansiYellow, "v"+version+" (newer)")
+	}
+	return me.colored(ansiGreen, "yes")
+}
+
+func (me showPrinter) bold(s string) string {
+	if me.color {
+		return gong.Bold(s)
+	}
+	return s
+}
+
+func (me showPrinter) underline(s string) string {
+	if me.color {
+		return gong.Underline(s)
+	}
+	return s
+}
+
+func (me showPrinter) colored(color, s string) string {
+	if me.color {
+		return color + s + ansiReset
+	}
+	return s
+}
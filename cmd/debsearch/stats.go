@@ -0,0 +1,69 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	ds "github.com/mark-summerfield/debsearch"
+	"github.com/mark-summerfield/gong"
+)
+
+func statsMain(args []string) {
+	parser := newCommandParser("stats", "Print statistics about the "+
+		"packages, e.g., how many there are, their total sizes, and "+
+		"how many are installed.")
+	inputOpts := newInputOptions(&parser)
+	if err := parser.ParseArgs(inputOpts.hoisted(args)); err != nil {
+		parser.OnError(err) // doesn't return
+	}
+	input := inputOpts.config(&parser)
+	t := time.Now()
+	model := input.readModel(true)
+	elapsed := time.Since(t)
+	installed, _ := ds.InstalledVersions(input.statusFile)
+	size := 0
+	downloadSize := 0
+	installedCount := 0
+	withTags := 0
+	withUrl := 0
+	withLongDesc := 0
+	countForArc := map[string]int{}
+	for _, deb := range model.Debs {
+		size += deb.Size
+		downloadSize += deb.DownloadSize
+		if _, ok := installed[ds.InstalledKey(deb.Name, deb.Arch)]; ok {
+			installedCount++
+		}
+		if !deb.Tags.IsEmpty() {
+			withTags++
+		}
+		if deb.Url != "" {
+			withUrl++
+		}
+		if !deb.LongDesc.IsEmpty() {
+			withLongDesc++
+		}
+		countForArc[deb.Arch]++
+	}
+	printStat("Packages", gong.Commas(len(model.Debs)))
+	printStat("Installed", gong.Commas(installedCount))
+	printStat("Components", gong.Commas(len(model.ComponentsAndCounts)))
+	printStat("Sections", gong.Commas(len(model.SectionsAndCounts)))
+	printStat("Tags", gong.Commas(len(model.TagsAndCounts)))
+	printStat("Tagged", gong.Commas(withTags))
+	printStat("With homepage", gong.Commas(withUrl))
+	printStat("With long desc.", gong.Commas(withLongDesc))
+	printStat("Installed size", ds.HumanSize(size))
+	printStat("Download size", ds.HumanBytes(downloadSize))
+	for _, arc := range gong.SortedMapKeys(countForArc) {
+		printStat("Arc "+arc, gong.Commas(countForArc[arc]))
+	}
+	printStat("Read in", elapsed.String())
+}
+
+func printStat(name, value string) {
+	fmt.Printf("%-16s %s\n", name+":", value)
+}
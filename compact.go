@@ -0,0 +1,234 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"math/bits"
+	"slices"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// stringTable gives each distinct string an ID and stores it once. The
+// tables are process-wide so that every model (and every goroutine
+// parsing into one) shares them.
+type stringTable struct {
+	mutex sync.RWMutex
+	ids   map[string]int
+	strs  []string
+}
+
+var (
+	tagTable     = &stringTable{ids: map[string]int{}}
+	sectionTable = &stringTable{ids: map[string]int{}}
+)
+
+// id returns s's ID, adding s to the table if it isn't already there.
+func (me *stringTable) id(s string) int {
+	me.mutex.RLock()
+	id, ok := me.ids[s]
+	me.mutex.RUnlock()
+	if ok {
+		return id
+	}
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if id, ok = me.ids[s]; !ok {
+		id = len(me.strs)
+		s = strings.Clone(s) // don't keep a whole file's text alive
+		me.ids[s] = id
+		me.strs = append(me.strs, s)
+	}
+	return id
+}
+
+func (me *stringTable) lookup(s string) (int, bool) {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+	id, ok := me.ids[s]
+	return id, ok
+}
+
+func (me *stringTable) str(id int) string {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+	return me.strs[id]
+}
+
+// snapshot returns the table's strings; since the table is append-only
+// the returned slice can be indexed without locking.
+func (me *stringTable) snapshot() []string {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+	return me.strs
+}
+
+// intern returns the table's copy of s.
+func (me *stringTable) intern(s string) string { return me.str(me.id(s)) }
+
+// TagSet is a compact set of tags: a bitset of IDs from a process-wide
+// tag table, so each tag's name is stored only once however many packages
+// have it. The zero value is an empty set.
+type TagSet struct {
+	bits []uint64
+}
+
+func NewTagSet(tags ...string) TagSet {
+	set := TagSet{}
+	set.Add(tags...)
+	return set
+}
+
+func (me *TagSet) Add(tags ...string) {
+	for _, tag := range tags {
+		me.addID(tagTable.id(tag))
+	}
+}
+
+func (me *TagSet) addID(id int) {
+	word := id / 64
+	if word >= len(me.bits) {
+		me.bits = slices.Grow(me.bits, word+1-len(me.bits))[:word+1]
+	}
+	me.bits[word] |= 1 << (id % 64)
+}
+
+func (me TagSet) Contains(tag string) bool {
+	id, ok := tagTable.lookup(tag)
+	return ok && me.hasID(id)
+}
+
+// HasFacet returns true if the set has any of the facet's tags, e.g.,
+// HasFacet("implemented-in") is true for a set with "implemented-in/c".
+func (me TagSet) HasFacet(facet string) bool {
+	strs := tagTable.snapshot()
+	for i, word := range me.bits {
+		for word != 0 {
+			bit := bits.TrailingZeros64(word)
+			if tag := strs[i*64+bit]; len(tag) > len(facet) &&
+				tag[len(facet)] == '/' && strings.HasPrefix(tag, facet) {
+				return true
+			}
+			word &= word - 1
+		}
+	}
+	return false
+}
+
+func (me TagSet) hasID(id int) bool {
+	word := id / 64
+	return word < len(me.bits) && me.bits[word]&(1<<(id%64)) != 0
+}
+
+func (me TagSet) Len() int {
+	count := 0
+	for _, word := range me.bits {
+		count += bits.OnesCount64(word)
+	}
+	return count
+}
+
+func (me TagSet) IsEmpty() bool {
+	for _, word := range me.bits {
+		if word != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (me *TagSet) Clear() { me.bits = nil }
+
+func (me TagSet) Copy() TagSet { return TagSet{slices.Clone(me.bits)} }
+
+// each calls fn for every tag in the set (in ID order).
+func (me TagSet) each(fn func(tag string)) {
+	strs := tagTable.snapshot()
+	for i, word := range me.bits {
+		for word != 0 {
+			bit := bits.TrailingZeros64(word)
+			fn(strs[i*64+bit])
+			word &= word - 1
+		}
+	}
+}
+
+func (me TagSet) ToSortedSlice() []string {
+	tags := make([]string, 0, me.Len())
+	me.each(func(tag string) { tags = append(tags, tag) })
+	slices.Sort(tags)
+	return tags
+}
+
+// pack copies those of the deb's strings that are substrings of a file's
+// text (including its raw long description) into a single allocation so
+// that the file's text can be freed (the interned strings are left as
+// they are).
+func (me *Deb) pack() {
+	fields := [...]*string{&me.Name, &me.Url, &me.ShortDesc, &me.Depends,
+		&me.PreDepends, &me.Recommends, &me.Suggests, &me.Conflicts,
+		&me.Breaks, &me.Replaces, &me.Provides, &me.Filename, &me.Sha256,
+		&me.LongDesc.text}
+	size := 0
+	for _, field := range fields {
+		size += len(*field)
+	}
+	if size == 0 {
+		return
+	}
+	buffer := make([]byte, 0, size)
+	for _, field := range fields {
+		if *field != "" {
+			start := len(buffer)
+			buffer = append(buffer, *field...)
+			*field = unsafe.String(&buffer[start], len(*field))
+		}
+	}
+}
+
+// Description is a package's long description. Those read from Packages
+// or Translation files are kept as (a copy of) the raw field text and
+// only converted when String is called.
+type Description struct {
+	text string
+	raw  bool
+}
+
+// NewDescription returns a Description of the given (already converted)
+// text, i.e., with empty lines rather than "." lines.
+func NewDescription(text string) Description {
+	return Description{text: text}
+}
+
+// rawDescription returns a Description of a Description field's raw
+// continuation lines.
+func rawDescription(raw string) Description {
+	return Description{text: raw, raw: raw != ""}
+}
+
+func (me Description) IsEmpty() bool { return me.text == "" }
+
+// String returns the description with the continuation lines' leading
+// space removed and "." lines as empty lines.
+func (me Description) String() string {
+	if !me.raw {
+		return me.text
+	}
+	var text strings.Builder
+	text.Grow(len(me.text))
+	rest := me.text
+	for rest != "" {
+		var line string
+		line, rest, _ = strings.Cut(rest, "\n")
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if line = trimRightWs(line[1:]); line != "." {
+			text.WriteString(line)
+		}
+		text.WriteByte('\n')
+	}
+	return strings.TrimRight(text.String(), asciiWs)
+}
@@ -0,0 +1,66 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/ulikunitz/xz"
+)
+
+// openIndex opens the given Packages or Translation file, transparently
+// decompressing it if it ends with .xz, .gz, or .bz2. The bytes read are
+// counted (before decompression) for progress reporting and reading stops
+// if ctx is cancelled.
+func openIndex(ctx context.Context, filename string) (io.ReadCloser,
+	error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	raw := NewContextReader(ctx, file)
+	var reader io.Reader
+	switch filepath.Ext(filename) {
+	case ".xz":
+		reader, err = xz.NewReader(raw)
+	case ".gz":
+		reader, err = gzip.NewReader(raw)
+	case ".bz2":
+		reader = bzip2.NewReader(raw)
+	default:
+		reader = raw
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &indexReader{reader, file}, nil
+}
+
+type indexReader struct {
+	io.Reader
+	file *os.File
+}
+
+// sizeHint returns the uncompressed size if it is known or 0.
+func (me *indexReader) sizeHint() int {
+	if _, ok := me.Reader.(*contextReader); ok { // not compressed
+		if info, err := me.file.Stat(); err == nil {
+			return int(info.Size())
+		}
+	}
+	return 0
+}
+
+func (me *indexReader) Close() error {
+	if closer, ok := me.Reader.(io.Closer); ok {
+		closer.Close()
+	}
+	return me.file.Close()
+}
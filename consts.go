@@ -12,20 +12,40 @@ import (
 var Version string
 
 const (
-	DefaultArc = "amd64"
+	DefaultArc    = "amd64"
+	MainComponent = "main"
 
 	Arcs = "alpha amd64 arm arm64 armel armhf avr32 hppa hurd-amd64 " +
 		"hurd-i386 i386 ia64 kfreebsd-amd64 kfreebsd-i386 m32 m68k mips " +
 		"mips64el mipsel netbsd-alpha netbsd-i386 or1k powerpc " +
 		"powerpcspe ppc64el riscv64 s390 s390x sh4 sparc sparc64 x32"
 
-	listsPath        = "/var/lib/apt/lists/"
-	packagePrefix    = "Package:"
-	packagePrefixLen = len(packagePrefix)
-	asciiWs          = " \f\n\r\t\v"
+	ListsPath      = "/var/lib/apt/lists/"
+	StatusPath     = "/var/lib/dpkg/status"
+	InfoPath       = "/var/lib/dpkg/info"
+	VocabularyPath = "/usr/share/debtags/vocabulary"
+	asciiWs        = " \f\n\r\t\v"
+
+	minChunkSize = 256 * 1024 // smaller Packages files aren't split
 )
 
 var (
 	Err101 = errors.New("E101: failed to open packages file")
 	Err102 = errors.New("E102: no package files given")
+	Err103 = errors.New("E103: failed to read Release file")
+	Err104 = errors.New("E104: failed to verify index file")
+	Err105 = errors.New("E105: failed to read dpkg status file")
+	Err106 = errors.New("E106: failed to read .deb file")
+	Err107 = errors.New("E107: failed to open translation file")
+	Err108 = errors.New("E108: failed to read any packages")
+	Err109 = errors.New("E109: failed to read debtags vocabulary")
+	Err110 = errors.New("E110: unknown sort key")
+	Err111 = errors.New("E111: unknown package kind")
+	Err112 = errors.New("E112: unknown ecosystem")
+	Err113 = errors.New("E113: failed to read manifest")
+	Err114 = errors.New("E114: failed to read ELF file")
+	Err115 = errors.New("E115: failed to read dpkg shlibs")
+	Err116 = errors.New("E116: failed to read Contents index")
+	Err117 = errors.New("E117: failed to read package list")
+	Err118 = errors.New("E118: failed to read lockfile")
 )
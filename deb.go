@@ -11,46 +11,88 @@ import (
 	"github.com/mark-summerfield/gset"
 )
 
-type deb struct {
-	Name      string
-	Version   string
-	Size      int
-	Url       string
-	Section   string
-	Tags      gset.Set[string]
-	ShortDesc string
-	LongDesc  string
+type Deb struct {
+	Name         string
+	Version      string
+	Size         int
+	Url          string
+	Section      string // without any component prefix, e.g., "libs"
+	Component    string // e.g., "main", "contrib", "non-free"
+	Tags         TagSet
+	ShortDesc    string
+	LongDesc     Description
+	Arch         string
+	Source       string
+	Maintainer   string
+	Priority     string
+	Depends      string
+	PreDepends   string
+	Recommends   string
+	Suggests     string
+	Conflicts    string
+	Breaks       string
+	Replaces     string
+	Provides     string
+	DownloadSize int
+	Filename     string
+	Sha256       string
 }
 
-func NewDeb() *deb { return &deb{Tags: gset.New[string]()} }
+func NewDeb() *Deb { return &Deb{} }
 
-func (me *deb) Copy() *deb {
-	return &deb{Name: me.Name, Version: me.Version, Size: me.Size,
-		Url: me.Url, Section: me.Section, Tags: me.Tags.Copy(),
-		ShortDesc: me.ShortDesc, LongDesc: me.LongDesc}
+func (me *Deb) Copy() *Deb {
+	return &Deb{Name: me.Name, Version: me.Version, Size: me.Size,
+		Url: me.Url, Section: me.Section, Component: me.Component,
+		Tags:      me.Tags.Copy(),
+		ShortDesc: me.ShortDesc, LongDesc: me.LongDesc, Arch: me.Arch,
+		Source: me.Source, Maintainer: me.Maintainer,
+		Priority: me.Priority, Depends: me.Depends,
+		PreDepends: me.PreDepends, Recommends: me.Recommends,
+		Suggests: me.Suggests, Conflicts: me.Conflicts, Breaks: me.Breaks,
+		Replaces: me.Replaces, Provides: me.Provides,
+		DownloadSize: me.DownloadSize, Filename: me.Filename,
+		Sha256: me.Sha256}
 }
 
-func (me *deb) Clear() {
+func (me *Deb) Clear() {
 	me.Name = ""
 	me.Version = ""
 	me.Size = 0
 	me.Url = ""
 	me.Section = ""
+	me.Component = ""
 	me.Tags.Clear()
 	me.ShortDesc = ""
-	me.LongDesc = ""
+	me.LongDesc = Description{}
+	me.Arch = ""
+	me.Source = ""
+	me.Maintainer = ""
+	me.Priority = ""
+	me.Depends = ""
+	me.PreDepends = ""
+	me.Recommends = ""
+	me.Suggests = ""
+	me.Conflicts = ""
+	me.Breaks = ""
+	me.Replaces = ""
+	me.Provides = ""
+	me.DownloadSize = 0
+	me.Filename = ""
+	me.Sha256 = ""
 }
 
-func (me *deb) IsValid() bool {
+func (me *Deb) IsValid() bool {
 	return me.Name != "" && me.Version != "" && me.Size > 0 &&
 		me.Section != "" && me.ShortDesc != ""
 }
 
-func (me *deb) Words() gset.Set[string] {
-	rx := regexp.MustCompile(`\W+`)
+var nonWordRx = regexp.MustCompile(`\W+`)
+
+func (me *Deb) Words() gset.Set[string] {
 	words := gset.New[string]()
-	for _, text := range []string{me.Name, me.ShortDesc, me.LongDesc} {
-		text = strings.ToLower(rx.ReplaceAllLiteralString(text, " "))
+	for _, text := range []string{me.Name, me.ShortDesc,
+		me.LongDesc.String()} {
+		text = strings.ToLower(nonWordRx.ReplaceAllLiteralString(text, " "))
 		for _, word := range strings.Fields(text) {
 			words.Add(word)
 		}
@@ -58,7 +100,38 @@ func (me *deb) Words() gset.Set[string] {
 	return words
 }
 
-func (me *deb) String() string {
+// QualifiedSection returns the section as a Packages file gives it, i.e.,
+// with its component prefix unless it is in main, e.g., "non-free/libs".
+func (me *Deb) QualifiedSection() string {
+	if me.Component == "" || me.Component == MainComponent {
+		return me.Section
+	}
+	return me.Component + "/" + me.Section
+}
+
+// SplitSection returns a Section field's component and section, e.g.,
+// "non-free" and "libs" for "non-free/libs"; a section without a
+// component prefix is in main.
+func SplitSection(value string) (string, string) {
+	component, section, found := strings.Cut(value, "/")
+	if !found {
+		return MainComponent, value
+	}
+	return component, section
+}
+
+// TagsByFacet returns the deb's tags grouped by their facet, e.g., tag
+// "use/viewing" is returned as "viewing" in the "use" facet's slice.
+func (me *Deb) TagsByFacet() map[string][]string {
+	tagsForFacet := map[string][]string{}
+	for _, tag := range me.Tags.ToSortedSlice() {
+		facet, name := SplitTag(tag)
+		tagsForFacet[facet] = append(tagsForFacet[facet], name)
+	}
+	return tagsForFacet
+}
+
+func (me *Deb) String() string {
 	return fmt.Sprintf("%s v%s %s %q %s", me.Name, me.Version,
 		HumanSize(me.Size), me.ShortDesc, me.Url)
 }
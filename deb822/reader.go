@@ -0,0 +1,149 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package deb822
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+const (
+	pgpMessageStart   = "-----BEGIN PGP SIGNED MESSAGE-----"
+	pgpSignatureStart = "-----BEGIN PGP SIGNATURE-----"
+)
+
+// Reader streams stanzas from an io.Reader. Comment lines (#...) are
+// skipped, CRLF line endings are accepted, and the armour of clearsigned
+// files such as InRelease is ignored (the signature isn't checked).
+type Reader struct {
+	Label   string // used in ParseErrors, e.g., the filename
+	reader  *bufio.Reader
+	lineNo  int
+	inArmor bool
+	done    bool
+}
+
+func NewReader(reader io.Reader) *Reader {
+	return &Reader{reader: bufio.NewReaderSize(reader, 64*1024)}
+}
+
+// NewReaderLabel returns a Reader whose ParseErrors start with the given
+// label (e.g., the filename).
+func NewReaderLabel(reader io.Reader, label string) *Reader {
+	stanzaReader := NewReader(reader)
+	stanzaReader.Label = label
+	return stanzaReader
+}
+
+// Next returns the next non-empty stanza, or io.EOF when there are none
+// left, or a *ParseError (or an I/O error) if the input is malformed.
+func (me *Reader) Next() (Stanza, error) {
+	stanza := Stanza{}
+	var value strings.Builder
+	flush := func() {
+		if len(stanza.Fields) > 0 {
+			stanza.Fields[len(stanza.Fields)-1].Value = value.String()
+		}
+		value.Reset()
+	}
+	for !me.done {
+		line, err := me.readLine()
+		if err != nil {
+			if err != io.EOF {
+				return stanza, err
+			}
+			me.done = true
+			if line == "" {
+				break
+			}
+		}
+		if me.skipArmor(line) || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			if len(stanza.Fields) > 0 {
+				flush()
+				return stanza, nil
+			}
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			if len(stanza.Fields) == 0 {
+				return stanza, me.errorf("continuation line without " +
+					"a field")
+			}
+			value.WriteByte('\n')
+			value.WriteString(strings.TrimRight(line[1:], " \t"))
+			continue
+		}
+		name, rest, found := strings.Cut(line, ":")
+		if !found {
+			return stanza, me.errorf("expected Name: value")
+		}
+		if name = strings.TrimSpace(name); name == "" {
+			return stanza, me.errorf("missing field name")
+		}
+		flush()
+		if len(stanza.Fields) == 0 {
+			stanza.Line = me.lineNo
+		}
+		stanza.Fields = append(stanza.Fields, Field{Name: name,
+			Line: me.lineNo})
+		value.WriteString(strings.TrimSpace(rest))
+	}
+	if len(stanza.Fields) > 0 {
+		flush()
+		return stanza, nil
+	}
+	return stanza, io.EOF
+}
+
+// All returns all the remaining stanzas.
+func (me *Reader) All() ([]Stanza, error) {
+	stanzas := []Stanza{}
+	for {
+		stanza, err := me.Next()
+		if err == io.EOF {
+			return stanzas, nil
+		}
+		if err != nil {
+			return stanzas, err
+		}
+		stanzas = append(stanzas, stanza)
+	}
+}
+
+func (me *Reader) readLine() (string, error) {
+	line, err := me.reader.ReadString('\n')
+	if line != "" {
+		me.lineNo++
+	}
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+// skipArmor returns true for the lines that make up a clearsigned file's
+// armour (and for the whole signature block).
+func (me *Reader) skipArmor(line string) bool {
+	if me.lineNo == 1 && line == pgpMessageStart {
+		me.inArmor = true // skip the Hash: header(s) up to a blank line
+		return true
+	}
+	if me.inArmor {
+		if strings.TrimSpace(line) == "" {
+			me.inArmor = false
+		}
+		return true
+	}
+	if line == pgpSignatureStart {
+		me.done = true
+		io.Copy(io.Discard, me.reader)
+		return true
+	}
+	return false
+}
+
+func (me *Reader) errorf(msg string) error {
+	return &ParseError{Label: me.Label, Line: me.lineNo, Msg: msg}
+}
@@ -0,0 +1,293 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package deb822
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+const packagesText = `Package: alpha
+Version: 1.0-1
+Description: the first package
+ Its long description's first paragraph
+ continues here.
+ .
+ And a second paragraph.
+
+Package: beta
+# a comment line
+Version: 2.0-1
+Depends: alpha (>= 1.0),
+ gamma
+`
+
+func readAll(t *testing.T, text string) []Stanza {
+	t.Helper()
+	stanzas, err := NewReader(strings.NewReader(text)).All()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return stanzas
+}
+
+func TestReaderContinuations(t *testing.T) {
+	stanzas := readAll(t, packagesText)
+	if len(stanzas) != 2 {
+		t.Fatalf("expected 2 stanzas, got %d", len(stanzas))
+	}
+	expected := "the first package\nIts long description's first " +
+		"paragraph\ncontinues here.\n.\nAnd a second paragraph."
+	if desc := stanzas[0].Get("Description"); desc != expected {
+		t.Errorf("expected description %q, got %q", expected, desc)
+	}
+	if depends := stanzas[1].Get("depends"); depends !=
+		"alpha (>= 1.0),\ngamma" {
+		t.Errorf("unexpected Depends %q", depends)
+	}
+	if stanzas[0].Line != 1 || stanzas[1].Line != 9 {
+		t.Errorf("expected stanza lines 1 and 9, got %d and %d",
+			stanzas[0].Line, stanzas[1].Line)
+	}
+	if line := stanzas[1].Fields[1].Line; line != 11 {
+		t.Errorf("expected Version on line 11, got %d", line)
+	}
+}
+
+func TestReaderComments(t *testing.T) {
+	stanzas := readAll(t, packagesText)
+	for _, field := range stanzas[1].Fields {
+		if strings.HasPrefix(field.Name, "#") {
+			t.Errorf("comment read as a field: %q", field.Name)
+		}
+	}
+	if version := stanzas[1].Get("Version"); version != "2.0-1" {
+		t.Errorf("expected Version 2.0-1, got %q", version)
+	}
+}
+
+func TestReaderCrlf(t *testing.T) {
+	crlf := strings.ReplaceAll(packagesText, "\n", "\r\n")
+	expected := readAll(t, packagesText)
+	stanzas := readAll(t, crlf)
+	if len(stanzas) != len(expected) {
+		t.Fatalf("expected %d stanzas, got %d", len(expected),
+			len(stanzas))
+	}
+	for i, stanza := range stanzas {
+		for j, field := range stanza.Fields {
+			want := expected[i].Fields[j]
+			if field.Name != want.Name || field.Value != want.Value {
+				t.Errorf("expected %s: %q, got %s: %q", want.Name,
+					want.Value, field.Name, field.Value)
+			}
+		}
+	}
+}
+
+func TestReaderInRelease(t *testing.T) {
+	text := `-----BEGIN PGP SIGNED MESSAGE-----
+Hash: SHA512
+
+Origin: Debian
+Suite: stable
+SHA256:
+ 0123abcd 1234 main/binary-amd64/Packages.xz
+ 4567cdef 567 main/i18n/Translation-en.gz
+-----BEGIN PGP SIGNATURE-----
+
+iQIzBAEBCgAdFiEE
+=abcd
+-----END PGP SIGNATURE-----
+`
+	stanzas := readAll(t, text)
+	if len(stanzas) != 1 {
+		t.Fatalf("expected 1 stanza, got %d", len(stanzas))
+	}
+	stanza := stanzas[0]
+	if _, ok := stanza.Lookup("Hash"); ok {
+		t.Error("the armour's Hash header was read as a field")
+	}
+	if suite := stanza.Get("Suite"); suite != "stable" {
+		t.Errorf("expected Suite stable, got %q", suite)
+	}
+	lines := strings.Split(stanza.Get("SHA256"), "\n")
+	if len(lines) != 3 || lines[0] != "" ||
+		lines[2] != "4567cdef 567 main/i18n/Translation-en.gz" {
+		t.Errorf("unexpected SHA256 %q", lines)
+	}
+	if stanza.Line != 4 {
+		t.Errorf("expected the stanza on line 4, got %d", stanza.Line)
+	}
+}
+
+func TestParseErrorLines(t *testing.T) {
+	for _, test := range []struct {
+		text string
+		line int
+		msg  string
+	}{
+		{" orphan\n", 1, "continuation line without a field"},
+		{"Package: a\n\nPackage: b\nno colon here\n", 4,
+			"expected Name: value"},
+		{"Package: a\n# comment\n: value\n", 3, "missing field name"},
+	} {
+		_, err := NewReaderLabel(strings.NewReader(test.text),
+			"Packages").All()
+		checkParseError(t, "Reader", err, test.line, test.msg)
+		// Scanning any chunking must give the same line numbers.
+		for n := 1; n <= 4; n++ {
+			err := scanAll(Split(test.text, n), func([]RawField) {})
+			checkParseError(t, fmt.Sprintf("Scan (n=%d)", n), err,
+				test.line, test.msg)
+		}
+	}
+}
+
+func checkParseError(t *testing.T, what string, err error, line int,
+	msg string) {
+	t.Helper()
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("%s: expected a *ParseError, got %v", what, err)
+	}
+	if parseErr.Line != line || parseErr.Msg != msg ||
+		parseErr.Label != "Packages" {
+		t.Errorf("%s: expected Packages:%d: %s, got %s", what, line, msg,
+			parseErr)
+	}
+}
+
+func scanAll(chunks []Chunk, fn func(fields []RawField)) error {
+	for _, chunk := range chunks {
+		if err := chunk.Scan("Packages", func(fields []RawField) error {
+			fn(fields)
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// manyStanzas returns count stanzas of varying lengths (including
+// continuation lines and comments) so that chunk edges fall mid-stanza.
+func manyStanzas(count int) string {
+	var text strings.Builder
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			text.WriteString("\n")
+		}
+		fmt.Fprintf(&text, "Package: pkg%d\nVersion: %d.0\n", i, i)
+		if i%3 == 0 {
+			text.WriteString("# comment\n")
+		}
+		fmt.Fprintf(&text, "Description: package %d\n", i)
+		for j := 0; j < i%5; j++ {
+			fmt.Fprintf(&text, " line %d of %d\n .\n", j, i)
+		}
+	}
+	return text.String()
+}
+
+func TestSplitBoundaries(t *testing.T) {
+	text := manyStanzas(50)
+	expected := readAll(t, text)
+	for n := 1; n <= 64; n++ {
+		chunks := Split(text, n)
+		if len(chunks) > n {
+			t.Errorf("n=%d: got %d chunks", n, len(chunks))
+		}
+		var joined strings.Builder
+		for i, chunk := range chunks {
+			if chunk.Offset != joined.Len() {
+				t.Errorf("n=%d: chunk %d at offset %d, expected %d", n, i,
+					chunk.Offset, joined.Len())
+			}
+			if i > 0 && !strings.HasPrefix(chunk.Text, "Package: ") {
+				t.Errorf("n=%d: chunk %d doesn't start at a stanza: %q",
+					n, i, chunk.Text[:min(20, len(chunk.Text))])
+			}
+			joined.WriteString(chunk.Text)
+		}
+		if joined.String() != text {
+			t.Fatalf("n=%d: the chunks don't make up the text", n)
+		}
+		var stanzas []Stanza
+		err := scanAll(chunks, func(fields []RawField) {
+			stanza := Stanza{}
+			for _, field := range fields {
+				stanza.Fields = append(stanza.Fields,
+					Field{Name: field.Name, Value: field.Text()})
+			}
+			stanzas = append(stanzas, stanza)
+		})
+		if err != nil {
+			t.Fatalf("n=%d: unexpected error: %s", n, err)
+		}
+		if len(stanzas) != len(expected) {
+			t.Fatalf("n=%d: expected %d stanzas, got %d", n, len(expected),
+				len(stanzas))
+		}
+		for i, stanza := range stanzas {
+			for _, field := range stanza.Fields {
+				if want := expected[i].Get(field.Name); field.Value != want {
+					t.Errorf("n=%d: stanza %d %s: expected %q, got %q", n,
+						i, field.Name, want, field.Value)
+				}
+			}
+		}
+	}
+}
+
+func TestSplitSmall(t *testing.T) {
+	if chunks := Split("", 4); len(chunks) != 1 || chunks[0].Text != "" {
+		t.Errorf("expected one empty chunk, got %v", chunks)
+	}
+	text := "Package: only\nVersion: 1\n"
+	if chunks := Split(text, 8); len(chunks) != 1 ||
+		chunks[0].Text != text {
+		t.Errorf("expected the whole text as one chunk, got %v", chunks)
+	}
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	stanzas := []Stanza{
+		{Fields: []Field{{Name: "Package", Value: "alpha"},
+			{Name: "Description", Value: "short\nfirst para\n\nsecond " +
+				"para\n  verbatim"}}},
+		{Fields: []Field{{Name: "Package", Value: "beta"},
+			{Name: "SHA256", Value: "\n 0123 45 Packages.xz"}}},
+	}
+	var buffer bytes.Buffer
+	writer := NewWriter(&buffer)
+	for _, stanza := range stanzas {
+		if err := writer.Write(stanza); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	reader := NewReader(&buffer)
+	for _, want := range stanzas {
+		got, err := reader.Next()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		for _, field := range want.Fields {
+			value := strings.ReplaceAll(field.Value, "\n\n", "\n.\n")
+			if got := got.Get(field.Name); got != value {
+				t.Errorf("%s: expected %q, got %q", field.Name, value, got)
+			}
+		}
+	}
+	if _, err := reader.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
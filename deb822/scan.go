@@ -0,0 +1,173 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package deb822
+
+import "strings"
+
+// RawField is a field whose Name and Value are substrings of the text
+// being scanned, so scanning doesn't allocate. The Value holds any
+// continuation lines as-is; use [RawField.Text] for the Value as a
+// [Reader] would give it.
+type RawField struct {
+	Name  string
+	Value string
+	Line  int // 1-based line number within the Chunk
+}
+
+// Text returns the field's value with each continuation line's leading
+// space and trailing whitespace removed (and any comment lines dropped).
+// Single-line values are returned without copying.
+func (me RawField) Text() string {
+	if strings.IndexByte(me.Value, '\n') == -1 {
+		return trimRightWs(me.Value)
+	}
+	var text strings.Builder
+	text.Grow(len(me.Value))
+	first, rest, _ := strings.Cut(me.Value, "\n")
+	text.WriteString(trimRightWs(first))
+	for rest != "" {
+		var line string
+		line, rest, _ = strings.Cut(rest, "\n")
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		text.WriteByte('\n')
+		text.WriteString(trimRightWs(line[1:]))
+	}
+	return text.String()
+}
+
+// Chunk is a stanza-aligned part of a text; see [Split].
+type Chunk struct {
+	Text   string
+	Offset int // the byte offset of Text in the text that was split
+	whole  string
+}
+
+// Split returns the text as at most n chunks, each starting at a stanza,
+// so that the chunks can be scanned concurrently. Clearsigned texts must
+// be read with a [Reader] instead.
+func Split(text string, n int) []Chunk {
+	chunks := make([]Chunk, 0, max(1, n))
+	size := len(text) / max(1, n)
+	start := 0
+	for start < len(text) {
+		end := len(text)
+		if len(chunks) < n-1 {
+			end = nextStanza(text, start+size)
+		}
+		chunks = append(chunks, Chunk{Text: text[start:end], Offset: start,
+			whole: text})
+		start = end
+	}
+	if len(chunks) == 0 {
+		chunks = append(chunks, Chunk{whole: text})
+	}
+	return chunks
+}
+
+// nextStanza returns the offset of the line following the first blank
+// line at or after the line containing pos (or len(text)).
+func nextStanza(text string, pos int) int {
+	if pos >= len(text) {
+		return len(text)
+	}
+	i := strings.LastIndexByte(text[:pos], '\n') + 1 // start of line
+	for i < len(text) {
+		end := strings.IndexByte(text[i:], '\n')
+		if end == -1 {
+			return len(text)
+		}
+		if isBlank(text[i : i+end]) {
+			return i + end + 1
+		}
+		i += end + 1
+	}
+	return len(text)
+}
+
+// Scan calls fn with the fields of each of the chunk's stanzas in turn,
+// stopping at the first error fn returns. The fields slice is reused, so
+// fn mustn't keep it (although it may keep the strings). Malformed input
+// is reported as a *ParseError numbered by line within the whole text.
+func (me Chunk) Scan(label string, fn func(fields []RawField) error) error {
+	fields := make([]RawField, 0, 24)
+	text := me.Text
+	lineNo := 0
+	valueStart := 0 // offset in text of the last field's value
+	for pos := 0; pos < len(text); {
+		line := text[pos:]
+		next := len(text)
+		if end := strings.IndexByte(line, '\n'); end != -1 {
+			line = line[:end]
+			next = pos + end + 1
+		}
+		lineNo++
+		switch {
+		case isBlank(line):
+			if len(fields) > 0 {
+				if err := fn(fields); err != nil {
+					return err
+				}
+				fields = fields[:0]
+			}
+		case line[0] == '#':
+		case line[0] == ' ' || line[0] == '\t':
+			if len(fields) == 0 {
+				return me.errorf(label, lineNo,
+					"continuation line without a field")
+			}
+			fields[len(fields)-1].Value = text[valueStart : pos+len(line)]
+		default:
+			colon := strings.IndexByte(line, ':')
+			if colon == -1 {
+				return me.errorf(label, lineNo, "expected Name: value")
+			}
+			name := strings.TrimSpace(line[:colon])
+			if name == "" {
+				return me.errorf(label, lineNo, "missing field name")
+			}
+			value := line[colon+1:]
+			for value != "" && (value[0] == ' ' || value[0] == '\t') {
+				value = value[1:]
+			}
+			valueStart = pos + len(line) - len(value)
+			fields = append(fields, RawField{Name: name, Value: value,
+				Line: lineNo})
+		}
+		pos = next
+	}
+	if len(fields) > 0 {
+		return fn(fields)
+	}
+	return nil
+}
+
+// errorf returns a ParseError for the given line of the chunk; the line
+// is converted to a line of the whole text only when there is an error.
+func (me Chunk) errorf(label string, lineNo int, msg string) error {
+	return &ParseError{Label: label, Msg: msg,
+		Line: strings.Count(me.whole[:me.Offset], "\n") + lineNo}
+}
+
+// trimRightWs is strings.TrimRight(s, " \t\r") without the overhead.
+func trimRightWs(s string) string {
+	end := len(s)
+	for end > 0 && (s[end-1] == ' ' || s[end-1] == '\t' ||
+		s[end-1] == '\r') {
+		end--
+	}
+	return s[:end]
+}
+
+func isBlank(line string) bool {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case ' ', '\t', '\r', '\n', '\f', '\v':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+// Package deb822 reads and writes the RFC 822-like stanzas used by
+// Debian's Packages, Translation, Release, Sources, and dpkg status files.
+package deb822
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Field's Value holds its continuation lines (if any) \n-separated and
+// without their leading space, so a Description's long description is
+// the lines after the first, with "." for empty lines.
+type Field struct {
+	Name  string
+	Value string
+	Line  int // 1-based line number of the field's first line
+}
+
+type Stanza struct {
+	Fields []Field
+	Line   int // 1-based line number of the stanza's first field
+}
+
+// Get returns the value of the named field (matched case-insensitively)
+// or "" if it isn't present.
+func (me *Stanza) Get(name string) string {
+	value, _ := me.Lookup(name)
+	return value
+}
+
+// Lookup returns the value of the named field (matched
+// case-insensitively) and true, or "" and false if it isn't present.
+func (me *Stanza) Lookup(name string) (string, bool) {
+	for _, field := range me.Fields {
+		if strings.EqualFold(field.Name, name) {
+			return field.Value, true
+		}
+	}
+	return "", false
+}
+
+// Set replaces the named field's value or appends the field if it isn't
+// present.
+func (me *Stanza) Set(name, value string) {
+	for i, field := range me.Fields {
+		if strings.EqualFold(field.Name, name) {
+			me.Fields[i].Value = value
+			return
+		}
+	}
+	me.Fields = append(me.Fields, Field{Name: name, Value: value})
+}
+
+func (me *Stanza) IsEmpty() bool { return len(me.Fields) == 0 }
+
+type ParseError struct {
+	Label string // e.g., a filename
+	Line  int
+	Msg   string
+}
+
+func (me *ParseError) Error() string {
+	if me.Label == "" {
+		return fmt.Sprintf("line %d: %s", me.Line, me.Msg)
+	}
+	return fmt.Sprintf("%s:%d: %s", me.Label, me.Line, me.Msg)
+}
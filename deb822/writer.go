@@ -0,0 +1,60 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package deb822
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// Writer writes stanzas separated by blank lines. Multi-line values are
+// written as continuation lines with empty lines written as " .". Call
+// Flush when done.
+type Writer struct {
+	writer *bufio.Writer
+	count  int
+}
+
+func NewWriter(writer io.Writer) *Writer {
+	return &Writer{writer: bufio.NewWriter(writer)}
+}
+
+func (me *Writer) Write(stanza Stanza) error {
+	if me.count > 0 {
+		if err := me.writer.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	me.count++
+	for _, field := range stanza.Fields {
+		if err := me.writeField(field.Name, field.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (me *Writer) writeField(name, value string) error {
+	lines := strings.Split(value, "\n")
+	if _, err := me.writer.WriteString(name + ":"); err != nil {
+		return err
+	}
+	if lines[0] != "" {
+		if _, err := me.writer.WriteString(" " + lines[0]); err != nil {
+			return err
+		}
+	}
+	for _, line := range lines[1:] {
+		if strings.TrimSpace(line) == "" {
+			line = "."
+		}
+		if _, err := me.writer.WriteString("\n " + line); err != nil {
+			return err
+		}
+	}
+	return me.writer.WriteByte('\n')
+}
+
+func (me *Writer) Flush() error { return me.writer.Flush() }
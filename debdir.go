@@ -0,0 +1,165 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mark-summerfield/debsearch/deb822"
+	"github.com/ulikunitz/xz"
+)
+
+const (
+	arMagic        = "!<arch>\n"
+	arHeaderSize   = 60
+	controlTarName = "control.tar"
+)
+
+// DebDir is the Source for the .deb files in a directory, e.g., a local
+// build's output or a directory of downloaded packages. Each deb's
+// Filename is its path and its SHA256 and DownloadSize are those of the
+// .deb file itself. A .deb that can't be read doesn't stop the others
+// being read; the failures are returned together (and so become one
+// [LoadError] warning).
+type DebDir struct {
+	Dir string
+}
+
+func NewDebDir(dir string) DebDir { return DebDir{Dir: dir} }
+
+func (me DebDir) Origin() Origin {
+	return Origin{Kind: "deb-dir", Location: me.Dir}
+}
+
+func (me DebDir) ReadDebs(ctx context.Context, add func(deb *Deb)) error {
+	filenames, err := filepath.Glob(filepath.Join(me.Dir, "*.deb"))
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, filename := range filenames {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		deb, err := ReadDebFile(filename)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if deb.IsValid() {
+			add(deb)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (me DebDir) ReadDescriptions(ctx context.Context,
+	add func(name string, longDesc Description)) error {
+	return nil // .deb control files have their long descriptions
+}
+
+// ReadDebFile returns the package described by the given .deb file's
+// control file.
+func ReadDebFile(filename string) (*Deb, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", Err106, err)
+	}
+	defer file.Close()
+	stanza, err := readControl(bufio.NewReader(file), filename)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %s", Err106, filename, err)
+	}
+	deb := debForStanza(&stanza)
+	deb.Filename = filename
+	if _, err := file.Seek(0, io.SeekStart); err == nil {
+		hash := sha256.New()
+		if size, err := io.Copy(hash, file); err == nil {
+			deb.DownloadSize = int(size)
+			deb.Sha256 = hex.EncodeToString(hash.Sum(nil))
+		}
+	}
+	return deb, nil
+}
+
+// readControl reads the control file from the control.tar member of the
+// given .deb (ar) archive.
+func readControl(reader *bufio.Reader, label string) (deb822.Stanza,
+	error) {
+	magic := make([]byte, len(arMagic))
+	if _, err := io.ReadFull(reader, magic); err != nil ||
+		string(magic) != arMagic {
+		return deb822.Stanza{}, fmt.Errorf("not a .deb file")
+	}
+	header := make([]byte, arHeaderSize)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			return deb822.Stanza{}, fmt.Errorf("no %s member",
+				controlTarName)
+		}
+		name := strings.TrimRight(strings.TrimSpace(string(header[:16])),
+			"/")
+		size, err := strconv.ParseInt(strings.TrimSpace(
+			string(header[48:58])), 10, 64)
+		if err != nil {
+			return deb822.Stanza{}, fmt.Errorf("corrupt ar header")
+		}
+		member := io.LimitReader(reader, size)
+		if strings.HasPrefix(name, controlTarName) {
+			return readControlTar(member, filepath.Ext(name), label)
+		}
+		if _, err := io.Copy(io.Discard, member); err != nil {
+			return deb822.Stanza{}, err
+		}
+		if size%2 == 1 { // members are 2-byte aligned
+			if _, err := reader.Discard(1); err != nil {
+				return deb822.Stanza{}, err
+			}
+		}
+	}
+}
+
+func readControlTar(member io.Reader, ext, label string) (deb822.Stanza,
+	error) {
+	var reader io.Reader
+	var err error
+	switch ext {
+	case ".gz":
+		reader, err = gzip.NewReader(member)
+	case ".xz":
+		reader, err = xz.NewReader(member)
+	case ".tar":
+		reader = member
+	case ".zst":
+		err = fmt.Errorf("%s%s: zstd compression is unsupported",
+			controlTarName, ext)
+	default:
+		err = fmt.Errorf("unsupported compression %s", ext)
+	}
+	if err != nil {
+		return deb822.Stanza{}, err
+	}
+	tarReader := tar.NewReader(reader)
+	for {
+		header, err := tarReader.Next()
+		if err != nil {
+			return deb822.Stanza{}, fmt.Errorf("no control file")
+		}
+		if strings.TrimPrefix(header.Name, "./") == "control" {
+			return deb822.NewReaderLabel(tarReader, label).Next()
+		}
+	}
+}
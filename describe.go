@@ -0,0 +1,316 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"html"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mark-summerfield/gong"
+)
+
+// Debian policy 5.6.13: a long description's lines that start with one
+// space are wrapped into paragraphs, those that start with more are shown
+// verbatim, and " ." lines are blank. Bullet lists aren't in the policy
+// but are common: lines like "  * item" are rendered as list items with
+// any more deeply indented lines that follow as their continuation.
+
+type descBlockKind uint8
+
+const (
+	paraBlock descBlockKind = iota
+	bulletBlock
+	verbatimBlock
+)
+
+// descBlock is a paragraph, a bullet item, or a run of verbatim lines;
+// its spans are the [start, end) byte offsets of its lines' text in the
+// description's text (see [Description.String]).
+type descBlock struct {
+	kind   descBlockKind
+	spans  [][2]int
+	level  int  // bullet nesting level, 0 for top-level items
+	indent int  // of the bullet's marker
+	gap    bool // preceded by a blank line
+}
+
+var urlRx = regexp.MustCompile(`\b(?:https?|ftp)://[^\s<>"]+`)
+
+// descBlocks returns the blocks of a long description's text.
+func descBlocks(text string) []descBlock {
+	var blocks []descBlock
+	var current *descBlock
+	var bulletIndents []int // of the current list's enclosing items
+	gap := false
+	flush := func() {
+		if current != nil {
+			blocks = append(blocks, *current)
+			current = nil
+		}
+	}
+	for start := 0; start < len(text); {
+		end := strings.IndexByte(text[start:], '\n')
+		if end == -1 {
+			end = len(text)
+		} else {
+			end += start
+		}
+		line := text[start:end]
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		switch marker := bulletMarker(line[indent:], indent); {
+		case indent == len(line): // blank
+			flush()
+			bulletIndents = bulletIndents[:0]
+			gap = true
+		case marker > 0:
+			flush()
+			for len(bulletIndents) > 0 &&
+				bulletIndents[len(bulletIndents)-1] >= indent {
+				bulletIndents = bulletIndents[:len(bulletIndents)-1]
+			}
+			current = &descBlock{kind: bulletBlock,
+				spans: [][2]int{{start + indent + marker, end}},
+				level: len(bulletIndents), indent: indent, gap: gap}
+			bulletIndents = append(bulletIndents, indent)
+			gap = false
+		case current != nil && current.kind == bulletBlock &&
+			indent > current.indent:
+			current.spans = append(current.spans, [2]int{start + indent,
+				end})
+		case indent == 0:
+			if current == nil || current.kind != paraBlock {
+				flush()
+				bulletIndents = bulletIndents[:0]
+				current = &descBlock{kind: paraBlock, gap: gap}
+				gap = false
+			}
+			current.spans = append(current.spans, [2]int{start, end})
+		default:
+			if current == nil || current.kind != verbatimBlock {
+				flush()
+				bulletIndents = bulletIndents[:0]
+				current = &descBlock{kind: verbatimBlock, gap: gap}
+				gap = false
+			}
+			current.spans = append(current.spans, [2]int{start, end})
+		}
+		start = end + 1
+	}
+	flush()
+	return blocks
+}
+
+// bulletMarker returns the length of the bullet marker (and the spaces
+// after it) that line (with its indent removed) starts with, or 0.
+func bulletMarker(line string, indent int) int {
+	if len(line) < 3 || (line[1] != ' ' && line[1] != '\t') {
+		return 0
+	}
+	switch line[0] {
+	case '*', '-', '+':
+	case 'o': // only accepted if indented since it is also a word
+		if indent == 0 {
+			return 0
+		}
+	default:
+		return 0
+	}
+	return len(line) - len(strings.TrimLeft(line[1:], " \t"))
+}
+
+// joined returns the block's spans' text joined by sep.
+func (me descBlock) joined(text, sep string) string {
+	parts := make([]string, 0, len(me.spans))
+	for _, span := range me.spans {
+		parts = append(parts, text[span[0]:span[1]])
+	}
+	return strings.Join(parts, sep)
+}
+
+// Text returns the description as plain text with its paragraphs and
+// bullet items reflowed to width and each line prefixed by indent.
+func (me Description) Text(width int, indent string) string {
+	text := me.String()
+	var result strings.Builder
+	for i, block := range descBlocks(text) {
+		if i > 0 {
+			result.WriteByte('\n')
+			if block.gap {
+				result.WriteByte('\n')
+			}
+		}
+		switch block.kind {
+		case paraBlock:
+			result.WriteString(gong.WrappedIndent(block.joined(text, " "),
+				width, indent))
+		case bulletBlock:
+			pad := indent + strings.Repeat("  ", block.level)
+			result.WriteString(gong.WrappedX(block.joined(text, " "), width,
+				pad+"• ", pad+"  "))
+		case verbatimBlock:
+			result.WriteString(indent + block.joined(text, "\n"+indent))
+		}
+	}
+	return result.String()
+}
+
+// Markdown returns the description as Markdown with its URLs as
+// autolinks.
+func (me Description) Markdown() string {
+	text := me.String()
+	var result strings.Builder
+	previous := paraBlock
+	for i, block := range descBlocks(text) {
+		if i > 0 {
+			result.WriteByte('\n')
+			if block.gap || block.kind != bulletBlock ||
+				previous != bulletBlock { // items of a list are adjacent
+				result.WriteByte('\n')
+			}
+		}
+		switch block.kind {
+		case paraBlock:
+			result.WriteString(markdownText(block.joined(text, " ")))
+		case bulletBlock:
+			result.WriteString(strings.Repeat("  ", block.level) + "- " +
+				markdownText(block.joined(text, " ")))
+		case verbatimBlock:
+			result.WriteString("```\n" + block.joined(text, "\n") + "\n```")
+		}
+		previous = block.kind
+	}
+	return result.String()
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "`", "\\`", `*`, `\*`,
+	`_`, `\_`, `[`, `\[`, `]`, `\]`, `<`, `\<`, `>`, `\>`)
+
+// EscapeMarkdown returns the text with Markdown's inline metacharacters
+// escaped.
+func EscapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+// markdownText returns the text escaped (including a leading block
+// marker) with its URLs as autolinks.
+func markdownText(text string) string {
+	var result strings.Builder
+	if text != "" && strings.IndexByte("#+-", text[0]) > -1 {
+		result.WriteByte('\\')
+	}
+	pos := 0
+	for _, span := range urlSpans(text, 0, len(text)) {
+		result.WriteString(EscapeMarkdown(text[pos:span[0]]))
+		result.WriteString("<" + text[span[0]:span[1]] + ">")
+		pos = span[1]
+	}
+	result.WriteString(EscapeMarkdown(text[pos:]))
+	return result.String()
+}
+
+// Html returns the description as HTML with its URLs as links and with
+// each of the matches (e.g., from [Query.Explain]) wrapped in before and
+// after, e.g., "<b>" and "</b>".
+func (me Description) Html(matches []WordMatch, before,
+	after string) string {
+	text := me.String()
+	var result strings.Builder
+	level := -1 // of the <ul> that is open
+	closeLists := func(to int) {
+		for ; level > to; level-- {
+			result.WriteString("</ul>\n")
+		}
+	}
+	for _, block := range descBlocks(text) {
+		if block.kind != bulletBlock {
+			closeLists(-1)
+		}
+		switch block.kind {
+		case paraBlock:
+			result.WriteString("<p>")
+			result.WriteString(htmlSpans(text, block.spans, "\n", matches,
+				before, after))
+			result.WriteString("</p>\n")
+		case bulletBlock:
+			closeLists(block.level)
+			for ; level < block.level; level++ {
+				result.WriteString("<ul>\n")
+			}
+			result.WriteString("<li>")
+			result.WriteString(htmlSpans(text, block.spans, "\n", matches,
+				before, after))
+			result.WriteString("</li>\n")
+		case verbatimBlock:
+			result.WriteString("<pre>")
+			result.WriteString(htmlSpans(text, block.spans, "\n", matches,
+				before, after))
+			result.WriteString("</pre>\n")
+		}
+	}
+	closeLists(-1)
+	return result.String()
+}
+
+// htmlSpans returns the spans of the text escaped, linkified, and with
+// the matches that are in them highlighted, joined by sep.
+func htmlSpans(text string, spans [][2]int, sep string,
+	matches []WordMatch, before, after string) string {
+	type tag struct {
+		pos     int
+		text    string
+		closing bool
+	}
+	parts := make([]string, 0, len(spans))
+	for _, span := range spans {
+		var tags []tag
+		for _, url := range urlSpans(text, span[0], span[1]) {
+			tags = append(tags, tag{url[0], `<a href="` +
+				html.EscapeString(text[url[0]:url[1]]) + `">`, false},
+				tag{url[1], "</a>", true})
+		}
+		for _, match := range matches {
+			if match.Start >= span[0] && match.End <= span[1] {
+				tags = append(tags, tag{match.Start, before, false},
+					tag{match.End, after, true})
+			}
+		}
+		slices.SortStableFunc(tags, func(a, b tag) int {
+			if a.pos != b.pos {
+				return a.pos - b.pos
+			}
+			if a.closing == b.closing {
+				return 0
+			}
+			if a.closing {
+				return -1
+			}
+			return 1
+		})
+		var part strings.Builder
+		pos := span[0]
+		for _, tag := range tags {
+			part.WriteString(html.EscapeString(text[pos:tag.pos]))
+			part.WriteString(tag.text)
+			pos = tag.pos
+		}
+		part.WriteString(html.EscapeString(text[pos:span[1]]))
+		parts = append(parts, part.String())
+	}
+	return strings.Join(parts, sep)
+}
+
+// urlSpans returns the [start, end) offsets of the URLs in
+// text[start:end] without any trailing punctuation.
+func urlSpans(text string, start, end int) [][2]int {
+	var spans [][2]int
+	for _, match := range urlRx.FindAllStringIndex(text[start:end], -1) {
+		url := strings.TrimRight(text[start+match[0]:start+match[1]],
+			".,;:!?)]}'")
+		spans = append(spans, [2]int{start + match[0],
+			start + match[0] + len(url)})
+	}
+	return spans
+}
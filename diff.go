@@ -0,0 +1,94 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"cmp"
+	"slices"
+)
+
+type ModelDiff struct {
+	Added      []NameVersion   `json:"added"`
+	Removed    []NameVersion   `json:"removed"`
+	Changed    []VersionChange `json:"changed"`
+	Sections   []CountChange   `json:"sections"`
+	Tags       []CountChange   `json:"tags"`
+	Components []CountChange   `json:"components"`
+}
+
+type NameVersion struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type VersionChange struct {
+	Name       string `json:"name"`
+	OldVersion string `json:"old_version"`
+	NewVersion string `json:"new_version"`
+}
+
+type CountChange struct {
+	Name     string `json:"name"`
+	OldCount int    `json:"old_count"`
+	NewCount int    `json:"new_count"`
+}
+
+func (me *ModelDiff) IsEmpty() bool {
+	return len(me.Added) == 0 && len(me.Removed) == 0 &&
+		len(me.Changed) == 0 && len(me.Sections) == 0 && len(me.Tags) == 0 &&
+		len(me.Components) == 0
+}
+
+// Diff returns the differences between this (old) model and the other
+// (new) model, with every slice sorted by name.
+func (me Model) Diff(other Model) ModelDiff {
+	diff := ModelDiff{Added: []NameVersion{}, Removed: []NameVersion{},
+		Changed: []VersionChange{}}
+	for name, deb := range me.Debs {
+		if otherDeb, ok := other.Debs[name]; !ok {
+			diff.Removed = append(diff.Removed,
+				NameVersion{name, deb.Version})
+		} else if otherDeb.Version != deb.Version {
+			diff.Changed = append(diff.Changed,
+				VersionChange{name, deb.Version, otherDeb.Version})
+		}
+	}
+	for name, deb := range other.Debs {
+		if _, ok := me.Debs[name]; !ok {
+			diff.Added = append(diff.Added, NameVersion{name, deb.Version})
+		}
+	}
+	slices.SortFunc(diff.Added, func(a, b NameVersion) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	slices.SortFunc(diff.Removed, func(a, b NameVersion) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	slices.SortFunc(diff.Changed, func(a, b VersionChange) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	diff.Sections = diffCounts(me.SectionsAndCounts, other.SectionsAndCounts)
+	diff.Tags = diffCounts(me.TagsAndCounts, other.TagsAndCounts)
+	diff.Components = diffCounts(me.ComponentsAndCounts,
+		other.ComponentsAndCounts)
+	return diff
+}
+
+func diffCounts(old, new map[string]int) []CountChange {
+	changes := []CountChange{}
+	for name, count := range old {
+		if newCount := new[name]; newCount != count {
+			changes = append(changes, CountChange{name, count, newCount})
+		}
+	}
+	for name, count := range new {
+		if _, ok := old[name]; !ok {
+			changes = append(changes, CountChange{name, 0, count})
+		}
+	}
+	slices.SortFunc(changes, func(a, b CountChange) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return changes
+}
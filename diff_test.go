@@ -0,0 +1,49 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	before := modelOf(t, `Package: kept
+Section: utils
+
+Package: upgraded
+Version: 1.0-1
+Section: utils
+
+Package: gone
+Section: libs
+Tag: role::shared-lib`)
+	after := modelOf(t, `Package: kept
+Section: utils
+
+Package: upgraded
+Version: 1.0-2
+Section: utils
+
+Package: added
+Section: contrib/games`)
+	want := ModelDiff{
+		Added:    []NameVersion{{"added", "1.0-1"}},
+		Removed:  []NameVersion{{"gone", "1.0-1"}},
+		Changed:  []VersionChange{{"upgraded", "1.0-1", "1.0-2"}},
+		Sections: []CountChange{{"games", 0, 1}, {"libs", 1, 0}},
+		Tags:     []CountChange{{"role/shared-lib", 1, 0}},
+		Components: []CountChange{{"contrib", 0, 1},
+			{MainComponent, 3, 2}}}
+	diff := before.Diff(after)
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("expected\n%+v\ngot\n%+v", want, diff)
+	}
+	if diff.IsEmpty() {
+		t.Error("expected a non-empty diff")
+	}
+	if same := after.Diff(after); !same.IsEmpty() {
+		t.Errorf("expected an empty diff, got %+v", same)
+	}
+}
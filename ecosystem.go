@@ -0,0 +1,233 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Ecosystem is a programming language's module registry.
+type Ecosystem uint8
+
+const (
+	EcosystemPyPI  Ecosystem = iota // Python: python3-*
+	EcosystemNpm                    // JavaScript: node-*
+	EcosystemCpan                   // Perl: lib*-perl
+	EcosystemGo                     // Go: golang-*-dev
+	EcosystemCrate                  // Rust: librust-*-dev
+)
+
+var ecosystemNames = [...]string{"pypi", "npm", "cpan", "go", "crate"}
+
+// EcosystemNames returns the names that [ParseEcosystem] accepts.
+func EcosystemNames() []string { return slices.Clone(ecosystemNames[:]) }
+
+// ParseEcosystem returns the Ecosystem of the given name, e.g., "pypi".
+func ParseEcosystem(name string) (Ecosystem, error) {
+	if i := slices.Index(ecosystemNames[:], name); i > -1 {
+		return Ecosystem(i), nil
+	}
+	return EcosystemPyPI, fmt.Errorf("%w: %q", Err112, name)
+}
+
+func (me Ecosystem) String() string {
+	if int(me) < len(ecosystemNames) {
+		return ecosystemNames[me]
+	}
+	return fmt.Sprintf("Ecosystem(%d)", me)
+}
+
+// Module is a name in a language ecosystem, e.g., PyPI's "requests",
+// npm's "@babel/core", CPAN's "JSON::XS", Go's "github.com/spf13/cobra",
+// or the crate "serde".
+type Module struct {
+	Ecosystem Ecosystem
+	Name      string
+}
+
+func (me Module) String() string {
+	return me.Ecosystem.String() + ":" + me.Name
+}
+
+// ParseModule returns the Module that [Module.String] returns as text,
+// e.g., "pypi:requests" or "cpan:JSON::XS".
+func ParseModule(text string) (Module, error) {
+	name, rest, found := strings.Cut(text, ":")
+	if !found || rest == "" {
+		return Module{}, fmt.Errorf("%w: %q (expected ECOSYSTEM:NAME)",
+			Err112, text)
+	}
+	ecosystem, err := ParseEcosystem(name)
+	if err != nil {
+		return Module{}, err
+	}
+	return Module{Ecosystem: ecosystem, Name: rest}, nil
+}
+
+// Resolver maps ecosystem module names to a model's packages using
+// Debian's naming conventions and the packages' Provides fields.
+type Resolver struct {
+	model     *Model
+	providers map[string][]*Deb // virtual package name to its providers
+}
+
+// NewResolver returns a resolver for the model's packages.
+func NewResolver(model *Model) *Resolver {
+	providers := map[string][]*Deb{}
+	for _, deb := range model.Debs {
+		for _, name := range deb.ProvidedNames() {
+			providers[name] = append(providers[name], deb)
+		}
+	}
+	for _, debs := range providers {
+		slices.SortFunc(debs, func(a, b *Deb) int {
+			return cmp.Compare(a.Name, b.Name)
+		})
+	}
+	return &Resolver{model: model, providers: providers}
+}
+
+// Resolve returns the packages that may provide the module, the most
+// likely first, or nil if there are none.
+func (me *Resolver) Resolve(module Module) []*Deb {
+	var debs []*Deb
+	seen := map[*Deb]bool{}
+	add := func(deb *Deb) {
+		if !seen[deb] {
+			seen[deb] = true
+			debs = append(debs, deb)
+		}
+	}
+	for _, name := range moduleCandidates(module) {
+		if deb, ok := me.model.Debs[name]; ok {
+			add(deb)
+		}
+		for _, deb := range me.providers[name] {
+			add(deb)
+		}
+	}
+	if len(debs) == 0 && module.Ecosystem == EcosystemGo {
+		for _, deb := range me.goSuffixMatches(module.Name) {
+			add(deb)
+		}
+	}
+	return debs
+}
+
+// goSuffixMatches returns the golang-*-dev packages whose names end with
+// the import path's last element (ignoring any major version suffix),
+// e.g., for a module hosted somewhere that Debian names differently; they
+// are sorted by name.
+func (me *Resolver) goSuffixMatches(path string) []*Deb {
+	path, _ = cutMajorVersion(strings.TrimSuffix(path, "/"))
+	suffix := "-" + debianName(path[strings.LastIndexByte(path, '/')+1:]) +
+		"-dev"
+	var debs []*Deb
+	for name, deb := range me.model.Debs {
+		if strings.HasPrefix(name, "golang-") &&
+			strings.HasSuffix(name, suffix) {
+			debs = append(debs, deb)
+		}
+	}
+	slices.SortFunc(debs, func(a, b *Deb) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return debs
+}
+
+// moduleCandidates returns the Debian package names that may provide the
+// module, the most likely first.
+func moduleCandidates(module Module) []string {
+	var names []string
+	add := func(name string) {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	switch name := module.Name; module.Ecosystem {
+	case EcosystemPyPI:
+		for _, name := range []string{pypiNormalized(name),
+			debianName(name)} {
+			add("python3-" + name)
+			for _, prefix := range []string{"python-", "py"} {
+				if rest, ok := strings.CutPrefix(name, prefix); ok &&
+					len(rest) > 1 {
+					add("python3-" + rest)
+				}
+			}
+			if rest, ok := strings.CutSuffix(name, "-python"); ok {
+				add("python3-" + rest)
+			}
+		}
+	case EcosystemNpm:
+		name = debianName(strings.TrimPrefix(name, "@")) // "/" → "-"
+		add("node-" + name)
+		add("node-" + strings.ReplaceAll(name, ".", "-"))
+		if strings.HasPrefix(name, "node-") {
+			add(name)
+		}
+	case EcosystemCpan:
+		parts := strings.Split(strings.ReplaceAll(name, "::", "-"), "-")
+		for i := len(parts); i > 0; i-- { // e.g., Foo::Bar then Foo
+			add("lib" + debianName(strings.Join(parts[:i], "-")) + "-perl")
+		}
+	case EcosystemGo:
+		for path := strings.TrimSuffix(name, "/"); strings.Contains(path,
+			"/"); path = path[:strings.LastIndexByte(path, '/')] {
+			add("golang-" + goPackageName(path) + "-dev")
+			if rest, ok := cutMajorVersion(path); ok {
+				add("golang-" + goPackageName(rest) + "-dev")
+			}
+		}
+	case EcosystemCrate:
+		add("librust-" + debianName(name) + "-dev")
+	}
+	return names
+}
+
+var (
+	pypiSeparatorRx     = regexp.MustCompile(`[-_.]+`)
+	goMajorVersionRx    = regexp.MustCompile(`/v[0-9]+$`)
+	debianNameInvalidRx = regexp.MustCompile(`[^a-z0-9.+-]+`)
+)
+
+// pypiNormalized returns the PyPI name normalized as PEP 503 specifies,
+// e.g., "Zope.Interface" → "zope-interface".
+func pypiNormalized(name string) string {
+	return pypiSeparatorRx.ReplaceAllLiteralString(strings.ToLower(name),
+		"-")
+}
+
+// debianName returns the name lowercased with any characters that aren't
+// valid in Debian package names replaced by "-".
+func debianName(name string) string {
+	return debianNameInvalidRx.ReplaceAllLiteralString(strings.ToLower(name),
+		"-")
+}
+
+// goPackageName returns the Go import path as the Debian Go team names
+// it, i.e., with the host's top-level domain dropped and "/"s as "-"s,
+// e.g., "github.com/spf13/cobra" → "github-spf13-cobra".
+func goPackageName(path string) string {
+	host, rest, _ := strings.Cut(path, "/")
+	if host == "google.golang.org" {
+		host = "google"
+	} else if i := strings.LastIndexByte(host, '.'); i > -1 {
+		host = host[:i]
+	}
+	return debianName(strings.ReplaceAll(host+"/"+rest, "/", "-"))
+}
+
+// cutMajorVersion returns the import path without a major version suffix
+// (e.g., "/v2") and true, or the path and false if it has none.
+func cutMajorVersion(path string) (string, bool) {
+	if loc := goMajorVersionRx.FindStringIndex(path); loc != nil {
+		return path[:loc[0]], true
+	}
+	return path, false
+}
@@ -0,0 +1,123 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	model := modelOf(t, `Package: python3-requests
+
+Package: python3-zope.interface
+
+Package: python3-yaml
+Provides: python3-pyyaml
+
+Package: node-lodash
+
+Package: node-babel-core
+
+Package: libjson-xs-perl
+
+Package: libjson-perl
+
+Package: golang-github-spf13-cobra-dev
+
+Package: golang-gopkg-yaml.v3-dev
+
+Package: golang-example-foo-dev
+
+Package: librust-serde-dev
+
+Package: librust-serde-json-dev`)
+	resolver := NewResolver(&model)
+	for _, test := range []struct {
+		module string
+		want   string
+	}{
+		{"pypi:requests", "python3-requests"},
+		{"pypi:Zope.Interface", "python3-zope.interface"},
+		{"pypi:PyYAML", "python3-yaml"}, // via Provides
+		{"npm:lodash", "node-lodash"},
+		{"npm:@babel/core", "node-babel-core"},
+		{"cpan:JSON::XS", "libjson-xs-perl libjson-perl"},
+		{"go:github.com/spf13/cobra", "golang-github-spf13-cobra-dev"},
+		{"go:github.com/spf13/cobra/doc", "golang-github-spf13-cobra-dev"},
+		{"go:gopkg.in/yaml.v3", "golang-gopkg-yaml.v3-dev"},
+		{"go:gitlab.com/someone/foo/v2", "golang-example-foo-dev"},
+		{"crate:serde_json", "librust-serde-json-dev"},
+		{"crate:no-such-crate", ""},
+	} {
+		module, err := ParseModule(test.module)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, deb := range resolver.Resolve(module) {
+			names = append(names, deb.Name)
+		}
+		if got := strings.Join(names, " "); got != test.want {
+			t.Errorf("%s: expected %q, got %q", module, test.want, got)
+		}
+	}
+}
+
+func TestParseModule(t *testing.T) {
+	module, err := ParseModule("cpan:JSON::XS")
+	if err != nil || module != (Module{EcosystemCpan, "JSON::XS"}) ||
+		module.String() != "cpan:JSON::XS" {
+		t.Errorf("unexpected module %v (%v)", module, err)
+	}
+	for _, text := range []string{"requests", "pypi:", "pip:requests"} {
+		if _, err := ParseModule(text); !errors.Is(err, Err112) {
+			t.Errorf("%q: expected Err112, got %v", text, err)
+		}
+	}
+}
+
+func TestReadManifest(t *testing.T) {
+	dir := t.TempDir()
+	for _, test := range []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"requirements.txt", "-r base.txt\nrequests>=2.0 # HTTP\n" +
+			"PyYAML\nhttps://example.org/x.whl\n",
+			[]string{"pypi:requests", "pypi:PyYAML"}},
+		{"package.json", `{"dependencies": {"lodash": "^4"},
+			"devDependencies": {"@babel/core": "^7", "lodash": "^4"}}`,
+			[]string{"npm:lodash", "npm:@babel/core"}},
+		{"cpanfile", "requires 'perl', '5.010';\nrequires 'JSON::XS';\n" +
+			"test_requires \"Test::More\"; # tests\n",
+			[]string{"cpan:JSON::XS", "cpan:Test::More"}},
+		{"go.mod", "module example.org/x\n\nrequire golang.org/x/text " +
+			"v0.3.0\nrequire (\n\tgithub.com/spf13/cobra v1.7.0 " +
+			"// indirect\n)\n",
+			[]string{"go:golang.org/x/text", "go:github.com/spf13/cobra"}},
+		{"Cargo.toml", "[package]\nname = \"x\"\n[dependencies]\n" +
+			"serde = \"1\"\njson = { package = \"serde_json\", " +
+			"version = \"1\" }\n[dev-dependencies]\nrand = \"0.8\"\n",
+			[]string{"crate:serde", "crate:serde_json", "crate:rand"}},
+	} {
+		filename := filepath.Join(dir, test.name)
+		writeFile(t, filename, []byte(test.text))
+		modules, err := ReadManifest(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, module := range modules {
+			got = append(got, module.String())
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.want, got)
+		}
+	}
+}
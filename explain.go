@@ -0,0 +1,105 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"slices"
+	"strings"
+)
+
+// The fields that a [WordMatch] can be in.
+const (
+	NameField      = "name"
+	ShortDescField = "short description"
+	LongDescField  = "long description"
+)
+
+// Explanation says why a [Query] matched a package: which of the query's
+// components, sections, kinds, tags, and words the package has.
+type Explanation struct {
+	Matched   bool
+	Component string      // "" if the query has no components
+	Section   string      // "" if the query has no sections
+	Kind      string      // "" if the query has no (included) kinds
+	Tags      []string    // the deb's tags that match the query's tags
+	Words     []WordMatch // in field order, then offset order
+}
+
+// WordMatch is an occurrence of one of a query's words: Start and End are
+// byte offsets into the field's text, i.e., into Deb.Name, Deb.ShortDesc,
+// or Deb.LongDesc.String().
+type WordMatch struct {
+	Word  string
+	Field string // NameField, ShortDescField, or LongDescField
+	Start int
+	End   int
+}
+
+// Explain returns which parts of the query the deb matches; its Matched
+// is what [Query.Match] would return.
+func (me *Query) Explain(deb *Deb) Explanation {
+	explanation := Explanation{Matched: me.Match(deb)}
+	if me.Components.Contains(deb.Component) {
+		explanation.Component = deb.Component
+	}
+	if me.matchesSection(deb) {
+		explanation.Section = deb.Section
+	}
+	if kind := deb.Kind(); me.Kinds.Contains(kind) {
+		explanation.Kind = kind.String()
+	}
+	if !me.Tags.IsEmpty() {
+		deb.Tags.each(func(tag string) {
+			facet, _ := SplitTag(tag)
+			if me.Tags.Contains(tag) || me.Tags.Contains(facet+"/*") {
+				explanation.Tags = append(explanation.Tags, tag)
+			}
+		})
+		slices.Sort(explanation.Tags)
+	}
+	if !me.Words.IsEmpty() {
+		for _, field := range [...]struct{ name, text string }{
+			{NameField, deb.Name}, {ShortDescField, deb.ShortDesc},
+			{LongDescField, deb.LongDesc.String()}} {
+			explanation.Words = append(explanation.Words,
+				me.wordMatches(field.name, field.text)...)
+		}
+	}
+	return explanation
+}
+
+// wordMatches returns where the query's words are in the text, splitting
+// it into words as [Deb.Words] does.
+func (me *Query) wordMatches(field, text string) []WordMatch {
+	var matches []WordMatch
+	for start := 0; start < len(text); {
+		if isNonWordRune(rune(text[start])) {
+			start++
+			continue
+		}
+		end := start + 1
+		for end < len(text) && !isNonWordRune(rune(text[end])) {
+			end++
+		}
+		// word runes are ASCII so folding them doesn't change offsets
+		if word := strings.ToLower(text[start:end]); me.Words.Contains(
+			word) {
+			matches = append(matches, WordMatch{Word: word, Field: field,
+				Start: start, End: end})
+		}
+		start = end
+	}
+	return matches
+}
+
+// MatchesIn returns the word matches that are in the given field.
+func (me Explanation) MatchesIn(field string) []WordMatch {
+	var matches []WordMatch
+	for _, match := range me.Words {
+		if match.Field == field {
+			matches = append(matches, match)
+		}
+	}
+	return matches
+}
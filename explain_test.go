@@ -0,0 +1,56 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	model := modelOf(t, `Package: vim-gtk3
+Section: editors
+Tag: role::program, uitoolkit::gtk, use::editing
+Description: Vi IMproved - enhanced vi editor - with GTK3 GUI
+ Vim is an almost compatible version of the UNIX editor Vi.`)
+	deb := model.Debs["vim-gtk3"]
+	query := NewQuery()
+	query.Components.Add(MainComponent)
+	query.AddSections("main/editors", "utils")
+	query.Kinds.Add(KindApplication)
+	query.Tags.Add("uitoolkit/*", "use/editing", "role/shared-lib")
+	query.Words.Add("vim", "editor", "emacs")
+	explanation := query.Explain(deb)
+	want := Explanation{Matched: true, Component: MainComponent,
+		Section: "editors", Kind: "application",
+		Tags: []string{"uitoolkit/gtk", "use/editing"},
+		Words: []WordMatch{
+			{"vim", NameField, 0, 3},
+			{"editor", ShortDescField, 26, 32},
+			{"vim", LongDescField, 0, 3},
+			{"editor", LongDescField, 48, 54}}}
+	if !reflect.DeepEqual(explanation, want) {
+		t.Fatalf("expected\n%+v\ngot\n%+v", want, explanation)
+	}
+	// The offsets are into the fields' text.
+	for _, match := range explanation.Words {
+		text := map[string]string{NameField: deb.Name,
+			ShortDescField: deb.ShortDesc,
+			LongDescField:  deb.LongDesc.String()}[match.Field]
+		if !strings.EqualFold(text[match.Start:match.End], match.Word) {
+			t.Errorf("%s@%d-%d is %q", match.Field, match.Start,
+				match.End, text[match.Start:match.End])
+		}
+	}
+	if got := explanation.MatchesIn(LongDescField); len(got) != 2 {
+		t.Errorf("expected 2 long description matches, got %v", got)
+	}
+	query.Sections.Clear()
+	query.AddSections("contrib/editors")
+	if explanation := query.Explain(deb); explanation.Matched ||
+		explanation.Section != "" {
+		t.Errorf("unexpected section match %+v", explanation)
+	}
+}
@@ -3,19 +3,121 @@
 
 package debsearch
 
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// FilePair is a Packages file and an optional Translation file (for long
+// descriptions), or, if PackagesReader is set, a Packages stream and an
+// optional Translation stream, in which case the filenames are ignored
+// and Label (if any) is used in error messages.
 type FilePair struct {
-	Packages string
-	I18n     string
+	Packages       string
+	I18n           string
+	PackagesReader io.Reader
+	I18nReader     io.Reader
+	Label          string
 }
 
 func NewFilePair(packages, i18n string) FilePair {
-	return FilePair{packages, i18n}
+	return FilePair{Packages: packages, I18n: i18n}
+}
+
+// NewReaderPair returns a FilePair that reads Packages data (e.g., the
+// output of apt-cache dumpavail, or an embedded fixture) from packages,
+// and long descriptions from i18n (which may be nil). The label is used
+// in error messages.
+func NewReaderPair(label string, packages, i18n io.Reader) FilePair {
+	return FilePair{PackagesReader: packages, I18nReader: i18n,
+		Label: label}
 }
 
 func StdFilePairs(arc string) []FilePair {
-	return stdFilePairs(arc, false)
+	return stdFilePairs(ListsPath, arc, false)
 }
 
 func StdFilePairsWithDescriptions(arc string) []FilePair {
-	return stdFilePairs(arc, true)
+	return stdFilePairs(ListsPath, arc, true)
+}
+
+// StdFilePairsIn is like [StdFilePairs] but reads the apt lists from
+// listsDir rather than from [ListsPath]; see also [ListsPathForRoot].
+func StdFilePairsIn(listsDir, arc string) []FilePair {
+	return stdFilePairs(listsDir, arc, false)
+}
+
+// StdFilePairsWithDescriptionsIn is like [StdFilePairsWithDescriptions]
+// but reads the apt lists from listsDir rather than from [ListsPath].
+func StdFilePairsWithDescriptionsIn(listsDir, arc string) []FilePair {
+	return stdFilePairs(listsDir, arc, true)
+}
+
+// ListsPathForRoot returns the apt lists directory for a chroot or an
+// image's root filesystem, e.g., root/var/lib/apt/lists/.
+func ListsPathForRoot(root string) string {
+	return filepath.Join(root, ListsPath)
+}
+
+func (me FilePair) hasI18n() bool {
+	return me.I18nReader != nil ||
+		(me.PackagesReader == nil && me.I18n != "")
+}
+
+func (me FilePair) openPackages(ctx context.Context) (io.ReadCloser, string,
+	error) {
+	if me.PackagesReader != nil {
+		return io.NopCloser(NewContextReader(ctx, me.PackagesReader)),
+			me.readerLabel(), nil
+	}
+	file, err := openIndex(ctx, me.Packages)
+	return file, me.Packages, err
+}
+
+func (me FilePair) openI18n(ctx context.Context) (io.ReadCloser, string,
+	error) {
+	if me.PackagesReader != nil {
+		return io.NopCloser(NewContextReader(ctx, me.I18nReader)),
+			me.readerLabel(), nil
+	}
+	file, err := openIndex(ctx, me.I18n)
+	return file, me.I18n, err
+}
+
+func (me FilePair) readerLabel() string {
+	if me.Label == "" {
+		return "<reader>"
+	}
+	return me.Label
+}
+
+func (me FilePair) Origin() Origin {
+	if me.PackagesReader != nil {
+		return Origin{Kind: "reader", Location: me.readerLabel()}
+	}
+	return Origin{Kind: "file", Location: me.Packages}
+}
+
+func (me FilePair) ReadDebs(ctx context.Context, add func(deb *Deb)) error {
+	file, label, err := me.openPackages(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %s", Err101, err)
+	}
+	defer file.Close()
+	return readStanzaDebs(ctx, file, label, add)
+}
+
+func (me FilePair) ReadDescriptions(ctx context.Context,
+	add func(name string, longDesc Description)) error {
+	if !me.hasI18n() {
+		return nil
+	}
+	file, label, err := me.openI18n(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %s", Err107, err)
+	}
+	defer file.Close()
+	return readDescriptions(ctx, file, label, add)
 }
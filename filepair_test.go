@@ -0,0 +1,38 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestStdFilePairsWithoutTranslation(t *testing.T) {
+	dir := t.TempDir()
+	prefix := filepath.Join(dir, "deb.debian.org_debian_dists_bookworm_")
+	for _, component := range []string{"main", "contrib"} {
+		writeFile(t, prefix+component+"_binary-amd64_Packages", nil)
+	}
+	// apt may not have fetched contrib's Translation file.
+	writeFile(t, prefix+"main_i18n_Translation-en", nil)
+	pairs := StdFilePairsWithDescriptionsIn(dir, "amd64")
+	if len(pairs) != 2 {
+		t.Fatalf("expected 2 pairs, got %d", len(pairs))
+	}
+	for _, pair := range pairs {
+		want := ""
+		if filepath.Base(pair.Packages) ==
+			filepath.Base(prefix)+"main_binary-amd64_Packages" {
+			want = prefix + "main_i18n_Translation-en"
+		}
+		if pair.I18n != want {
+			t.Errorf("%s: expected Translation %q, got %q", pair.Packages,
+				want, pair.I18n)
+		}
+	}
+	model := newModelOrFail(t, pairs...) // no Translation warnings
+	if len(model.Debs) != 0 {
+		t.Errorf("expected no packages, got %d", len(model.Debs))
+	}
+}
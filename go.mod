@@ -8,6 +8,7 @@ require (
 	github.com/mark-summerfield/gong v1.5.0
 	github.com/mark-summerfield/gset v1.1.0
 	github.com/pwiecz/go-fltk v0.0.0-20231004200521-fcb439dedbaf
+	github.com/ulikunitz/xz v0.5.12
 )
 
 require (
@@ -0,0 +1,127 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Kind is what sort of package a [Deb] is, as guessed from its name,
+// section, tags, and short description by [Deb.Kind].
+type Kind uint8
+
+const (
+	KindApplication    Kind = iota // anything not of another kind
+	KindLibrary                    // shared libraries
+	KindDevelopment                // headers and static libraries (-dev)
+	KindDocumentation              // -doc packages
+	KindDebug                      // debug symbols (-dbg and -dbgsym)
+	KindTransitional               // transitional, dummy, and metapackages
+	KindFont                       // fonts-*
+	KindLanguageModule             // e.g., python3-*, lib*-perl, golang-*
+	KindFirmware                   // firmware-*
+)
+
+var kindNames = [...]string{"application", "library", "development",
+	"documentation", "debug", "transitional", "font", "language-module",
+	"firmware"}
+
+// KindNames returns the names that [ParseKind] accepts.
+func KindNames() []string { return slices.Clone(kindNames[:]) }
+
+// ParseKind returns the Kind of the given name, e.g., "debug".
+func ParseKind(name string) (Kind, error) {
+	if i := slices.Index(kindNames[:], name); i > -1 {
+		return Kind(i), nil
+	}
+	return KindApplication, fmt.Errorf("%w: %q", Err111, name)
+}
+
+func (me Kind) String() string {
+	if int(me) < len(kindNames) {
+		return kindNames[me]
+	}
+	return fmt.Sprintf("Kind(%d)", me)
+}
+
+var (
+	languageSections = []string{"golang", "haskell", "java", "javascript",
+		"lisp", "ocaml", "perl", "php", "python", "ruby", "rust", "gnu-r"}
+	languagePrefixes = []string{"python3-", "python-", "ruby-", "node-",
+		"golang-", "php-", "r-cran-", "r-bioc-", "librust-", "libghc-",
+		"elpa-", "lua-", "libocaml-"}
+	languageSuffixes = []string{"-perl", "-java", "-ocaml-dev"}
+)
+
+// Kind returns what sort of package the deb is; the checks are made in
+// order so that, e.g., python3-foo-dbg is debug rather than a language
+// module, and librust-foo-dev is a language module rather than
+// development.
+func (me *Deb) Kind() Kind {
+	name := me.Name
+	switch {
+	case strings.HasSuffix(name, "-dbg") ||
+		strings.HasSuffix(name, "-dbgsym") || me.Section == "debug":
+		return KindDebug
+	case me.Section == "metapackages" || me.Tags.Contains("role/dummy") ||
+		me.Tags.Contains("role/metapackage") ||
+		isTransitionalDesc(me.ShortDesc):
+		return KindTransitional
+	case strings.HasSuffix(name, "-doc") ||
+		strings.HasSuffix(name, "-docs") || me.Section == "doc" ||
+		me.Tags.Contains("role/documentation"):
+		return KindDocumentation
+	case strings.HasPrefix(name, "firmware-") ||
+		strings.HasSuffix(name, "-firmware") ||
+		strings.Contains(name, "-firmware-"):
+		return KindFirmware
+	case me.Section == "fonts" || strings.HasPrefix(name, "fonts-") ||
+		strings.HasPrefix(name, "xfonts-") ||
+		me.Tags.Contains("made-of/font"):
+		return KindFont
+	case slices.Contains(languageSections, me.Section) ||
+		hasAnyPrefix(name, languagePrefixes) ||
+		hasAnySuffix(name, languageSuffixes):
+		return KindLanguageModule
+	case strings.HasSuffix(name, "-dev") ||
+		strings.HasSuffix(name, "-headers") || me.Section == "libdevel" ||
+		me.Tags.Contains("role/devel-lib"):
+		return KindDevelopment
+	case me.Section == "libs" || me.Tags.Contains("role/shared-lib") ||
+		me.Tags.Contains("devel/library") || sonameRx.MatchString(name):
+		return KindLibrary
+	}
+	return KindApplication
+}
+
+// sonameRx matches shared library package names, e.g., libc6, libssl3,
+// libgtk-3-0, or libfoo1t64, but not libreoffice or libvirt-daemon.
+var sonameRx = regexp.MustCompile(`^lib[a-z0-9.+-]*[0-9](?:t64)?$`)
+
+func isTransitionalDesc(shortDesc string) bool {
+	shortDesc = strings.ToLower(shortDesc)
+	return strings.Contains(shortDesc, "transitional") ||
+		strings.Contains(shortDesc, "dummy package")
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnySuffix(s string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(s, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestKind(t *testing.T) {
+	model := modelOf(t, `Package: vim
+
+Package: libc6
+Section: libs
+
+Package: libssl3t64
+
+Package: libreoffice
+Section: editors
+
+Package: libxml2
+Section: non-free/libs
+
+Package: libfoo-dev
+Section: libdevel
+
+Package: libgtk-3-dev
+
+Package: python3-requests
+Section: python
+
+Package: python3-requests-dbg
+Section: python
+
+Package: librust-serde-dev
+Section: rust
+
+Package: libjson-xs-perl
+Section: perl
+
+Package: vim-doc
+Section: doc
+
+Package: git-man
+Tag: role::documentation
+
+Package: exim4
+Section: metapackages
+
+Package: old-name
+Description: transitional package for new-name
+
+Package: firmware-iwlwifi
+Section: non-free-firmware/kernel
+
+Package: fonts-dejavu
+Section: fonts
+
+Package: coreutils-dbgsym
+Section: debug`)
+	for name, want := range map[string]Kind{
+		"vim":                  KindApplication,
+		"libc6":                KindLibrary,
+		"libssl3t64":           KindLibrary, // by its name
+		"libreoffice":          KindApplication,
+		"libxml2":              KindLibrary, // libs in non-free
+		"libfoo-dev":           KindDevelopment,
+		"libgtk-3-dev":         KindDevelopment,
+		"python3-requests":     KindLanguageModule,
+		"python3-requests-dbg": KindDebug,
+		"librust-serde-dev":    KindLanguageModule,
+		"libjson-xs-perl":      KindLanguageModule,
+		"vim-doc":              KindDocumentation,
+		"git-man":              KindDocumentation,
+		"exim4":                KindTransitional,
+		"old-name":             KindTransitional,
+		"firmware-iwlwifi":     KindFirmware,
+		"fonts-dejavu":         KindFont,
+		"coreutils-dbgsym":     KindDebug,
+	} {
+		if got := model.Debs[name].Kind(); got != want {
+			t.Errorf("%s: expected %s, got %s", name, want, got)
+		}
+	}
+}
+
+func TestParseKind(t *testing.T) {
+	for _, name := range KindNames() {
+		if kind, err := ParseKind(name); err != nil || kind.String() !=
+			name {
+			t.Errorf("%s: got %s (%v)", name, kind, err)
+		}
+	}
+	if _, err := ParseKind("games"); !errors.Is(err, Err111) {
+		t.Errorf("expected Err111, got %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// LoadOptions control how [NewModelContext] reads its sources. If Workers
+// is 0, NumCPU is used.
+type LoadOptions struct {
+	Workers  int            // max sources read (and chunks parsed) at once
+	Progress func(Progress) // if not nil, called as each source is done
+}
+
+// Progress is passed to a [LoadOptions] Progress callback each time a
+// source has been completely read. The callback is never called
+// concurrently but is called from a worker goroutine, so GUIs must pass
+// the values to their UI thread. Sources rather than files are counted
+// since a source may read many files (e.g., an [AptLists] source) or none.
+type Progress struct {
+	Origin         Origin        // the source just read
+	Elapsed        time.Duration // how long the source took to read
+	SourcesDone    int           // including the source just read
+	SourcesTotal   int
+	BytesRead      int64 // so far, across all sources
+	PackagesParsed int   // so far, across all sources
+}
+
+// LoadError is a failure to read (all or part of) a source's packages or
+// descriptions. If some sources could be read, [NewModelContext] returns
+// the model of what was read with its failures in [Model] Warnings;
+// otherwise it returns them all joined with [Err108].
+type LoadError struct {
+	Origin       Origin
+	Descriptions bool // true if the failure was reading descriptions
+	Err          error
+}
+
+func (me *LoadError) Error() string {
+	what := "packages"
+	if me.Descriptions {
+		what = "descriptions"
+	}
+	return fmt.Sprintf("%s (%s): %s", me.Origin, what, me.Err)
+}
+
+func (me *LoadError) Unwrap() error { return me.Err }
+
+type loadStatsKey struct{}
+
+type loadStats struct {
+	bytesRead      atomic.Int64
+	packagesParsed atomic.Int64
+}
+
+func statsFor(ctx context.Context) *loadStats {
+	stats, _ := ctx.Value(loadStatsKey{}).(*loadStats)
+	return stats
+}
+
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+	stats  *loadStats
+}
+
+// NewContextReader returns a reader that fails with ctx.Err() once ctx is
+// cancelled and that counts the bytes it reads toward [Progress]
+// BytesRead when used by a [Source] read by [NewModelContext].
+func NewContextReader(ctx context.Context, reader io.Reader) io.Reader {
+	return &contextReader{ctx: ctx, reader: reader, stats: statsFor(ctx)}
+}
+
+func (me *contextReader) Read(buffer []byte) (int, error) {
+	if err := me.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := me.reader.Read(buffer)
+	if me.stats != nil {
+		me.stats.bytesRead.Add(int64(n))
+	}
+	return n, err
+}
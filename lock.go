@@ -0,0 +1,275 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mark-summerfield/debsearch/deb822"
+)
+
+// LockEntry is a package resolved to the exact .deb that apt would fetch.
+type LockEntry struct {
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	Arch     string `json:"arch"`
+	Filename string `json:"filename"`
+	Sha256   string `json:"sha256"`
+	Size     int    `json:"size"` // the .deb's (download) size
+}
+
+func newLockEntry(deb *Deb) LockEntry {
+	return LockEntry{Name: deb.Name, Version: deb.Version, Arch: deb.Arch,
+		Filename: deb.Filename, Sha256: deb.Sha256, Size: deb.DownloadSize}
+}
+
+// Lockfile is the resolved packages, sorted by name; it is written the
+// same way for the same packages so that it can be regenerated
+// deterministically.
+type Lockfile struct {
+	Packages []LockEntry `json:"packages"`
+}
+
+// Lock returns a lockfile of the named packages for the given arc (a name
+// may be qualified with another, e.g., "libc6:i386") and, if any relation
+// kinds are given, of what they (recursively) have relations of those
+// kinds to, plus the names (or for dependencies, the relations, e.g.,
+// "libfoo (>= 2)") that couldn't be resolved. Packages are resolved
+// against every version read for their arc (the highest first). A
+// relation qualified with an arc, e.g., "libc6:i386", is resolved for that
+// arc, one qualified with ":native" for the given arc, and otherwise for
+// the depending package's arc. Of a relation's alternatives the first
+// whose package exists and satisfies it is used, or else the first (by
+// name) that provides it.
+func (me *Model) Lock(arc string, names []string,
+	kinds ...RelationKind) (Lockfile, []string) {
+	var providers map[string][]*Deb // only needed for dependencies
+	if len(kinds) > 0 {
+		providers = map[string][]*Deb{}
+		for name := range me.Debs {
+			for _, deb := range me.Versions(name) {
+				for _, provided := range deb.ProvidedNames() {
+					providers[provided] = append(providers[provided], deb)
+				}
+			}
+		}
+	}
+	locked := map[string]*Deb{} // keyed by name:arch
+	var unresolved []string
+	var pending []*Deb
+	for _, text := range names {
+		name, nameArc, _ := strings.Cut(text, ":")
+		if nameArc == "" {
+			nameArc = arc
+		}
+		if debs := me.VersionsFor(name, nameArc); len(debs) > 0 {
+			pending = append(pending, debs[0])
+		} else if !slices.Contains(unresolved, text) {
+			unresolved = append(unresolved, text)
+		}
+	}
+	for len(pending) > 0 {
+		deb := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		key := deb.Name + ":" + deb.Arch
+		if _, ok := locked[key]; ok {
+			continue
+		}
+		locked[key] = deb
+		for _, kind := range kinds {
+			for _, group := range deb.Relations(kind) {
+				target := me.lockTarget(group, deb, arc, providers)
+				if target != nil {
+					pending = append(pending, target)
+				} else if text := relationGroupString(
+					group); !slices.Contains(unresolved, text) {
+					unresolved = append(unresolved, text)
+				}
+			}
+		}
+	}
+	lockfile := Lockfile{Packages: make([]LockEntry, 0, len(locked))}
+	for _, deb := range locked {
+		lockfile.Packages = append(lockfile.Packages, newLockEntry(deb))
+	}
+	slices.SortFunc(lockfile.Packages, func(a, b LockEntry) int {
+		if a.Name != b.Name {
+			return cmp.Compare(a.Name, b.Name)
+		}
+		return cmp.Compare(a.Arch, b.Arch)
+	})
+	return lockfile, unresolved
+}
+
+// lockTarget returns the package that satisfies one of the group's
+// alternatives for the arc it applies to (see [Model.Lock]) or nil.
+func (me *Model) lockTarget(group []Relation, deb *Deb, arc string,
+	providers map[string][]*Deb) *Deb {
+	for _, relation := range group {
+		for _, target := range me.VersionsFor(relation.Name,
+			relationArc(relation, deb, arc)) {
+			if relation.SatisfiedBy(target.Version) {
+				return target
+			}
+		}
+	}
+	for _, relation := range group {
+		targetArc := relationArc(relation, deb, arc)
+		var target *Deb
+		for _, provider := range providers[relation.Name] {
+			if provider.Arch != targetArc && provider.Arch != "all" &&
+				provider.Arch != "" {
+				continue
+			}
+			if target == nil || provider.Name < target.Name ||
+				(provider.Name == target.Name && CompareVersions(
+					provider.Version, target.Version) > 0) {
+				target = provider
+			}
+		}
+		if target != nil {
+			return target
+		}
+	}
+	return nil
+}
+
+// relationArc returns the arc that the deb's relation is to be resolved
+// for: its own if it has one, otherwise the deb's (or the given arc if
+// the deb is arc-independent or the relation is for the native arc).
+func relationArc(relation Relation, deb *Deb, arc string) string {
+	switch relation.Arch {
+	case "", "any":
+		if deb.Arch != "" && deb.Arch != "all" {
+			return deb.Arch
+		}
+		return arc
+	case "native":
+		return arc
+	}
+	return relation.Arch
+}
+
+func relationGroupString(group []Relation) string {
+	alternatives := make([]string, 0, len(group))
+	for _, relation := range group {
+		alternatives = append(alternatives, relation.String())
+	}
+	return strings.Join(alternatives, " | ")
+}
+
+// WriteJson writes the lockfile as indented JSON.
+func (me *Lockfile) WriteJson(writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(me)
+}
+
+// WriteDeb822 writes the lockfile as one Packages-style stanza per
+// package.
+func (me *Lockfile) WriteDeb822(writer io.Writer) error {
+	stanzaWriter := deb822.NewWriter(writer)
+	for _, entry := range me.Packages {
+		stanza := deb822.Stanza{}
+		stanza.Set("Package", entry.Name)
+		stanza.Set("Version", entry.Version)
+		stanza.Set("Architecture", entry.Arch)
+		stanza.Set("Filename", entry.Filename)
+		stanza.Set("SHA256", entry.Sha256)
+		stanza.Set("Size", strconv.Itoa(entry.Size))
+		if err := stanzaWriter.Write(stanza); err != nil {
+			return err
+		}
+	}
+	return stanzaWriter.Flush()
+}
+
+// ReadLockfile returns the lockfile written (as JSON or deb822) to the
+// given file.
+func ReadLockfile(filename string) (Lockfile, error) {
+	raw, err := os.ReadFile(filename)
+	if err != nil {
+		return Lockfile{}, fmt.Errorf("%w: %s", Err118, err)
+	}
+	var lockfile Lockfile
+	if text := bytes.TrimSpace(raw); len(text) > 0 && text[0] == '{' {
+		if err := json.Unmarshal(raw, &lockfile); err != nil {
+			return Lockfile{}, fmt.Errorf("%w: %s: %s", Err118, filename,
+				err)
+		}
+		return lockfile, nil
+	}
+	stanzas, err := deb822.NewReaderLabel(bytes.NewReader(raw),
+		filename).All()
+	if err != nil {
+		return Lockfile{}, fmt.Errorf("%w: %s", Err118, err)
+	}
+	for _, stanza := range stanzas {
+		size, _ := strconv.Atoi(stanza.Get("Size"))
+		lockfile.Packages = append(lockfile.Packages, LockEntry{
+			Name: stanza.Get("Package"), Version: stanza.Get("Version"),
+			Arch: stanza.Get("Architecture"), Filename: stanza.Get("Filename"),
+			Sha256: stanza.Get("SHA256"), Size: size})
+	}
+	return lockfile, nil
+}
+
+// LockDrift is a locked package that no longer matches the model: Deb is
+// nil if the package is no longer available (or ArcMissing is true if
+// none of the packages read are for its arc, so it couldn't be checked),
+// otherwise its version or .deb differs.
+type LockDrift struct {
+	Entry      LockEntry
+	Deb        *Deb
+	ArcMissing bool
+}
+
+// VerifyLock returns the lockfile's packages that have drifted from the
+// model's, in lockfile order; a package hasn't drifted if any version read
+// for its arc (e.g., from an earlier suite) is the locked one.
+func (me *Model) VerifyLock(lockfile Lockfile) []LockDrift {
+	var drifts []LockDrift
+	arcsRead := map[string]bool{}
+	for _, entry := range lockfile.Packages {
+		var debs []*Deb
+		for _, deb := range me.VersionsFor(entry.Name, entry.Arch) {
+			if deb.Arch == entry.Arch {
+				debs = append(debs, deb)
+			}
+		}
+		if len(debs) == 0 {
+			arcRead, ok := arcsRead[entry.Arch]
+			if !ok {
+				arcRead = entry.Arch == "all" || entry.Arch == "" ||
+					me.HasArc(entry.Arch)
+				arcsRead[entry.Arch] = arcRead
+			}
+			drifts = append(drifts, LockDrift{Entry: entry,
+				ArcMissing: !arcRead})
+			continue
+		}
+		if slices.ContainsFunc(debs, func(deb *Deb) bool {
+			return deb.Version == entry.Version &&
+				deb.Filename == entry.Filename && deb.Sha256 == entry.Sha256
+		}) {
+			continue
+		}
+		deb := debs[0] // the highest version unless the locked one exists
+		if index := slices.IndexFunc(debs, func(deb *Deb) bool {
+			return deb.Version == entry.Version
+		}); index > -1 {
+			deb = debs[index]
+		}
+		drifts = append(drifts, LockDrift{Entry: entry, Deb: deb})
+	}
+	return drifts
+}
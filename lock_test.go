@@ -0,0 +1,185 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// lockPackages are amd64 and i386 packages with the fields a lockfile
+// needs; app depends on libfoo1 for its own arc and on a tool for the
+// native arc.
+const lockPackages = `Package: app
+Version: 2.0-1
+Depends: libfoo1 (>= 1.1), tool:native, virtual-mta
+
+Package: app
+Version: 1.0-1
+Depends: libfoo1
+
+Package: libfoo1
+Version: 1.2-1
+Depends: libc6
+
+Package: libfoo1
+Version: 1.2-1
+Architecture: i386
+Depends: libc6
+
+Package: libc6
+Version: 2.36-9
+
+Package: libc6
+Version: 2.36-9
+Architecture: i386
+
+Package: tool
+Version: 3.0-1
+
+Package: mta-b
+Provides: virtual-mta
+
+Package: mta-a
+Provides: virtual-mta
+
+Package: data
+Architecture: all`
+
+// lockModel returns a model of lockPackages with each package's Filename,
+// SHA256, and Size derived from its name, version, and arch, so that
+// changing any of them changes its .deb.
+func lockModel(t *testing.T, packages string) Model {
+	t.Helper()
+	var text strings.Builder
+	for _, stanza := range strings.Split(packages, "\n\n") {
+		name, version, arch := "", "1.0-1", "amd64"
+		for _, line := range strings.Split(stanza, "\n") {
+			key, value, _ := strings.Cut(line, ": ")
+			switch key {
+			case "Package":
+				name = value
+			case "Version":
+				version = value
+			case "Architecture":
+				arch = value
+			}
+		}
+		fmt.Fprintf(&text, "%s\nFilename: pool/%s_%s_%s.deb\n"+
+			"SHA256: %x\nSize: %d\n\n", stanza, name, version, arch,
+			name+version+arch, len(name+version))
+	}
+	return modelOf(t, text.String())
+}
+
+func lockedNames(lockfile Lockfile) string {
+	names := make([]string, 0, len(lockfile.Packages))
+	for _, entry := range lockfile.Packages {
+		names = append(names, entry.Name+":"+entry.Arch+"="+entry.Version)
+	}
+	return strings.Join(names, " ")
+}
+
+func TestLock(t *testing.T) {
+	model := lockModel(t, lockPackages)
+	for _, test := range []struct {
+		names      []string
+		kinds      []RelationKind
+		want       string
+		unresolved []string
+	}{
+		{[]string{"app", "data"}, nil, "app:amd64=2.0-1 data:all=1.0-1",
+			nil},
+		{[]string{"libfoo1:i386", "libfoo1", "nosuch", "app:arm64"}, nil,
+			"libfoo1:amd64=1.2-1 libfoo1:i386=1.2-1",
+			[]string{"nosuch", "app:arm64"}},
+		{[]string{"app"}, []RelationKind{DependsKind},
+			"app:amd64=2.0-1 libc6:amd64=2.36-9 libfoo1:amd64=1.2-1 " +
+				"mta-a:amd64=1.0-1 tool:amd64=3.0-1", nil},
+		{[]string{"libfoo1:i386"}, []RelationKind{DependsKind},
+			"libc6:i386=2.36-9 libfoo1:i386=1.2-1", nil},
+	} {
+		lockfile, unresolved := model.Lock("amd64", test.names,
+			test.kinds...)
+		if got := lockedNames(lockfile); got != test.want {
+			t.Errorf("%v: expected %q, got %q", test.names, test.want, got)
+		}
+		if !reflect.DeepEqual(unresolved, test.unresolved) {
+			t.Errorf("%v: expected unresolved %v, got %v", test.names,
+				test.unresolved, unresolved)
+		}
+	}
+}
+
+// TestLockRoundTrip writes a lockfile in both formats, reads it back, and
+// verifies it against the model it came from and against changed models.
+func TestLockRoundTrip(t *testing.T) {
+	model := lockModel(t, lockPackages)
+	lockfile, unresolved := model.Lock("amd64", []string{"app",
+		"libfoo1:i386", "data"}, DependsKind)
+	if len(unresolved) > 0 || len(lockfile.Packages) != 8 {
+		t.Fatalf("unexpected lock %q (unresolved %v)",
+			lockedNames(lockfile), unresolved)
+	}
+	dir := t.TempDir()
+	for _, format := range []string{"json", "deb822"} {
+		var buffer bytes.Buffer
+		write := lockfile.WriteDeb822
+		if format == "json" {
+			write = lockfile.WriteJson
+		}
+		if err := write(&buffer); err != nil {
+			t.Fatal(err)
+		}
+		filename := filepath.Join(dir, "lock."+format)
+		writeFile(t, filename, buffer.Bytes())
+		read, err := ReadLockfile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(read, lockfile) {
+			t.Errorf("%s: read back\n%+v\nexpected\n%+v", format, read,
+				lockfile)
+		}
+		if drifts := model.VerifyLock(read); len(drifts) > 0 {
+			t.Errorf("%s: unexpected drifts %+v", format, drifts)
+		}
+	}
+	newer := lockModel(t, strings.Replace(strings.Replace(lockPackages,
+		"Package: tool\nVersion: 3.0-1", "Package: tool\nVersion: 3.1-1",
+		1), "Package: mta-a\nProvides: virtual-mta\n\n", "", 1))
+	amd64Only := lockModel(t, strings.ReplaceAll(lockPackages,
+		"Architecture: i386", "Architecture: amd64"))
+	for _, test := range []struct {
+		name  string
+		model Model
+		want  string
+	}{
+		{"newer", newer, "mta-a v1.0-1: removed; tool v3.0-1: now v3.1-1"},
+		{"amd64-only", amd64Only, "libc6 v2.36-9: i386 not read; " +
+			"libfoo1 v1.2-1: i386 not read"},
+	} {
+		var drifts []string
+		for _, drift := range test.model.VerifyLock(lockfile) {
+			entry := drift.Entry
+			text := fmt.Sprintf("%s v%s: ", entry.Name, entry.Version)
+			switch {
+			case drift.ArcMissing:
+				text += entry.Arch + " not read"
+			case drift.Deb == nil:
+				text += "removed"
+			default:
+				text += "now v" + drift.Deb.Version
+			}
+			drifts = append(drifts, text)
+		}
+		if got := strings.Join(drifts, "; "); got != test.want {
+			t.Errorf("%s: expected %q, got %q", test.name, test.want, got)
+		}
+	}
+}
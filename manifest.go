@@ -0,0 +1,198 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mark-summerfield/gong"
+)
+
+// ReadManifest returns the modules that a project's manifest requires;
+// the manifest's kind is given by its name: requirements*.txt (PyPI),
+// package.json (npm), cpanfile (CPAN), go.mod (Go), or Cargo.toml
+// (crates).
+func ReadManifest(filename string) ([]Module, error) {
+	raw, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", Err113, err)
+	}
+	var modules []Module
+	switch base := filepath.Base(filename); {
+	case base == "package.json":
+		modules, err = readPackageJson(raw)
+	case base == "cpanfile":
+		modules = readCpanfile(raw)
+	case base == "go.mod":
+		modules = readGoMod(raw)
+	case base == "Cargo.toml":
+		modules = readCargoToml(raw)
+	case strings.HasSuffix(base, ".txt"):
+		modules = readRequirements(raw)
+	default:
+		err = fmt.Errorf("unrecognized manifest (expected "+
+			"requirements.txt, package.json, cpanfile, go.mod, or "+
+			"Cargo.toml): %s", filename)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", Err113, err)
+	}
+	return modules, nil
+}
+
+// manifestLines returns the raw text's lines each with any comment (from
+// the first commentStart) and surrounding whitespace removed and with
+// empty lines dropped.
+func manifestLines(raw []byte, commentStart string) []string {
+	var result []string
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), commentStart)
+		if line = strings.TrimSpace(line); line != "" {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+var requirementRx = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*`)
+
+// readRequirements reads a pip requirements file; options (e.g., -r
+// other.txt) and URLs are skipped.
+func readRequirements(raw []byte) []Module {
+	var modules []Module
+	for _, line := range manifestLines(raw, "#") {
+		if strings.HasPrefix(line, "-") || strings.Contains(line, "://") {
+			continue
+		}
+		if name := requirementRx.FindString(line); name != "" {
+			modules = append(modules, Module{EcosystemPyPI, name})
+		}
+	}
+	return modules
+}
+
+func readPackageJson(raw []byte) ([]Module, error) {
+	var manifest struct {
+		Dependencies         map[string]string `json:"dependencies"`
+		DevDependencies      map[string]string `json:"devDependencies"`
+		PeerDependencies     map[string]string `json:"peerDependencies"`
+		OptionalDependencies map[string]string `json:"optionalDependencies"`
+	}
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return nil, err
+	}
+	var modules []Module
+	seen := map[string]bool{}
+	for _, dependencies := range []map[string]string{
+		manifest.Dependencies, manifest.DevDependencies,
+		manifest.PeerDependencies, manifest.OptionalDependencies} {
+		for _, name := range gong.SortedMapKeys(dependencies) {
+			if !seen[name] {
+				seen[name] = true
+				modules = append(modules, Module{EcosystemNpm, name})
+			}
+		}
+	}
+	return modules, nil
+}
+
+var cpanRequiresRx = regexp.MustCompile(`\b(?:(?:test|build|configure|` +
+	`author)_)?(?:requires|recommends|suggests)\s*\(?\s*['"]([^'"]+)['"]`)
+
+// readCpanfile reads a cpanfile's requires (and recommends and suggests)
+// except for perl itself.
+func readCpanfile(raw []byte) []Module {
+	var modules []Module
+	for _, line := range manifestLines(raw, "#") {
+		for _, match := range cpanRequiresRx.FindAllStringSubmatch(line,
+			-1) {
+			if match[1] != "perl" {
+				modules = append(modules, Module{EcosystemCpan, match[1]})
+			}
+		}
+	}
+	return modules
+}
+
+// readGoMod reads a go.mod's require directives (both single-line and
+// block forms).
+func readGoMod(raw []byte) []Module {
+	var modules []Module
+	inBlock := false
+	for _, line := range manifestLines(raw, "//") {
+		switch {
+		case line == "require (":
+			inBlock = true
+		case inBlock && line == ")":
+			inBlock = false
+		case inBlock:
+			modules = append(modules, Module{EcosystemGo,
+				strings.Fields(line)[0]})
+		case strings.HasPrefix(line, "require "):
+			if fields := strings.Fields(line); len(fields) > 1 {
+				modules = append(modules, Module{EcosystemGo, fields[1]})
+			}
+		}
+	}
+	return modules
+}
+
+var (
+	cargoTableRx   = regexp.MustCompile(`^\[(.+)\]$`)
+	cargoPackageRx = regexp.MustCompile(`\bpackage\s*=\s*"([^"]+)"`)
+)
+
+// readCargoToml reads the crates in a Cargo.toml's dependencies,
+// dev-dependencies, and build-dependencies tables (including
+// target-specific ones and [dependencies.NAME] tables); renamed
+// dependencies are given by their package names.
+func readCargoToml(raw []byte) []Module {
+	var modules []Module
+	seen := map[string]bool{}
+	add := func(name string) {
+		name = strings.Trim(name, `"' `)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			modules = append(modules, Module{EcosystemCrate, name})
+		}
+	}
+	inDependencies := false
+	for _, line := range manifestLines(raw, "#") {
+		if match := cargoTableRx.FindStringSubmatch(line); match != nil {
+			table := match[1]
+			inDependencies = false
+			for _, kind := range []string{"dependencies",
+				"dev-dependencies", "build-dependencies"} {
+				if table == kind || strings.HasSuffix(table, "."+kind) {
+					inDependencies = true
+				} else if i := strings.Index(table, kind+"."); i > -1 &&
+					(i == 0 || table[i-1] == '.') {
+					add(table[i+len(kind)+1:]) // [dependencies.NAME]
+				}
+			}
+			continue
+		}
+		if inDependencies {
+			key, value, found := strings.Cut(line, "=")
+			if !found {
+				continue
+			}
+			if match := cargoPackageRx.FindStringSubmatch(
+				value); match != nil {
+				add(match[1])
+			} else {
+				add(key)
+			}
+		}
+	}
+	return modules
+}
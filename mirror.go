@@ -0,0 +1,211 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/mark-summerfield/debsearch/deb822"
+	"github.com/mark-summerfield/gong"
+)
+
+// Compressed variants are preferred to uncompressed ones since mirrors
+// often only carry the compressed files.
+var indexExts = []string{".xz", ".gz", ".bz2", ""}
+
+// Mirror describes a local (possibly partial) Debian mirror laid out as
+// Root/dists/<suite>/<component>/binary-<arc>/Packages[.xz|.gz|.bz2] with
+// long descriptions in Root/dists/<suite>/<component>/i18n/. A mirror
+// made by [NewMirror] (and its copies) only finds and verifies its files
+// once, when it is first read as a [Source].
+type Mirror struct {
+	Root       string
+	Suites     []string
+	Components []string
+	Arc        string
+	Verify     bool // if true check every file against its Release file
+	indexes    *mirrorIndexes
+}
+
+// mirrorIndexes are a mirror's files as found (and verified) for reading.
+type mirrorIndexes struct {
+	once     sync.Once
+	pairs    []FilePair
+	errs     []error // Release and Packages file failures
+	descErrs []error // Translation file failures
+}
+
+func NewMirror(root, arc string, suites, components []string) Mirror {
+	return Mirror{Root: root, Suites: suites, Components: components,
+		Arc: arc, Verify: true, indexes: &mirrorIndexes{}}
+}
+
+// FilePairs returns the Packages and Translation-en files found in the
+// mirror for its suites, components and arc. If Verify is true then any
+// file that is missing from its suite's Release file or whose SHA256
+// doesn't match is dropped and reported in the returned error.
+func (me Mirror) FilePairs(withDescriptions bool) ([]FilePair, error) {
+	indexes := &mirrorIndexes{}
+	me.findIndexes(indexes, withDescriptions)
+	return indexes.pairs, errors.Join(append(indexes.errs,
+		indexes.descErrs...)...)
+}
+
+// readIndexes returns the mirror's files (with their Translation files),
+// finding and verifying them only the first time.
+func (me Mirror) readIndexes() *mirrorIndexes {
+	indexes := me.indexes
+	if indexes == nil { // not made by NewMirror
+		indexes = &mirrorIndexes{}
+	}
+	indexes.once.Do(func() { me.findIndexes(indexes, true) })
+	return indexes
+}
+
+func (me Mirror) findIndexes(indexes *mirrorIndexes,
+	withDescriptions bool) {
+	pairs := []FilePair{}
+	var errs, descErrs []error
+	for _, suite := range me.Suites {
+		suiteDir := filepath.Join(me.Root, "dists", suite)
+		var sums map[string]string
+		if me.Verify {
+			var err error
+			if sums, err = readReleaseSums(suiteDir); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		for _, component := range me.Components {
+			pkgFile, err := me.findIndex(suiteDir, sums, path.Join(
+				component, "binary-"+me.Arc, "Packages"))
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if pkgFile == "" {
+				continue // this suite doesn't have this component
+			}
+			descFile := ""
+			if withDescriptions {
+				descFile, err = me.findIndex(suiteDir, sums, path.Join(
+					component, "i18n", "Translation-en"))
+				if err != nil {
+					descErrs = append(descErrs, err)
+				}
+			}
+			pairs = append(pairs, NewFilePair(pkgFile, descFile))
+		}
+	}
+	indexes.pairs, indexes.errs, indexes.descErrs = pairs, errs, descErrs
+}
+
+// findIndex returns the first existing compressed or uncompressed variant
+// of the given index (relative to suiteDir), or "" if there isn't one.
+func (me Mirror) findIndex(suiteDir string, sums map[string]string,
+	name string) (string, error) {
+	for _, ext := range indexExts {
+		filename := filepath.Join(suiteDir, filepath.FromSlash(name+ext))
+		if !gong.FileExists(filename) {
+			continue
+		}
+		if me.Verify {
+			if err := verifySha256(filename, sums[name+ext]); err != nil {
+				return "", err
+			}
+		}
+		return filename, nil
+	}
+	return "", nil
+}
+
+// readReleaseSums returns the SHA256 sums in the suite's InRelease or
+// Release file keyed by path relative to suiteDir.
+func readReleaseSums(suiteDir string) (map[string]string, error) {
+	var file *os.File
+	var err error
+	for _, name := range []string{"InRelease", "Release"} {
+		if file, err = os.Open(filepath.Join(suiteDir, name)); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", Err103, err)
+	}
+	defer file.Close()
+	sums := map[string]string{}
+	stanza, err := deb822.NewReaderLabel(file, file.Name()).Next()
+	if err != nil {
+		return sums, fmt.Errorf("%w: %s", Err103, err)
+	}
+	for _, line := range strings.Split(stanza.Get("SHA256"), "\n") {
+		if fields := strings.Fields(line); len(fields) == 3 {
+			sums[fields[2]] = fields[0]
+		}
+	}
+	return sums, nil
+}
+
+func verifySha256(filename, want string) error {
+	if want == "" {
+		return fmt.Errorf("%w: %s: not listed in Release file", Err104,
+			filename)
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("%w: %s", Err104, err)
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return fmt.Errorf("%w: %s", Err104, err)
+	}
+	if got := hex.EncodeToString(hash.Sum(nil)); got != want {
+		return fmt.Errorf("%w: %s: SHA256 mismatch", Err104, filename)
+	}
+	return nil
+}
+
+func (me Mirror) Origin() Origin {
+	return Origin{Kind: "mirror", Location: me.Root}
+}
+
+// ReadDebs reads the packages from every index that could be found and
+// verified; the error reports every Release or Packages file that
+// couldn't.
+func (me Mirror) ReadDebs(ctx context.Context, add func(deb *Deb)) error {
+	indexes := me.readIndexes()
+	err := errors.Join(indexes.errs...)
+	if len(indexes.pairs) == 0 {
+		return errors.Join(err, Err102)
+	}
+	return errors.Join(err, readAll(ctx, indexes.pairs,
+		func(pair FilePair) error { return pair.ReadDebs(ctx, add) }))
+}
+
+// ReadDescriptions reads the long descriptions from every Translation
+// file that could be found and verified; the error reports every one that
+// couldn't.
+func (me Mirror) ReadDescriptions(ctx context.Context,
+	add func(name string, longDesc Description)) error {
+	indexes := me.readIndexes()
+	err := errors.Join(indexes.descErrs...)
+	if len(indexes.pairs) == 0 {
+		return err
+	}
+	return errors.Join(err, readAll(ctx, indexes.pairs,
+		func(pair FilePair) error {
+			return pair.ReadDescriptions(ctx, add)
+		}))
+}
@@ -0,0 +1,80 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeMirror writes a mirror's bookworm suite with a main Packages file
+// and Translation-en file; only those listed are in its Release file.
+func writeMirror(t *testing.T, listed ...string) string {
+	t.Helper()
+	root := t.TempDir()
+	suiteDir := filepath.Join(root, "dists", "bookworm")
+	var packages, translation bytes.Buffer
+	for i := 0; i < 3; i++ {
+		writeStanza(&packages, &translation, i, "1.0", "amd64", true)
+	}
+	files := map[string][]byte{
+		"main/binary-amd64/Packages": packages.Bytes(),
+		"main/i18n/Translation-en":   translation.Bytes()}
+	release := "Suite: bookworm\nSHA256:\n"
+	for _, name := range listed {
+		filename := filepath.Join(suiteDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, filename, files[name])
+		release += fmt.Sprintf(" %x %d %s\n", sha256.Sum256(files[name]),
+			len(files[name]), name)
+	}
+	writeFile(t, filepath.Join(suiteDir, "Release"), []byte(release))
+	return root
+}
+
+func TestMirrorSource(t *testing.T) {
+	root := writeMirror(t, "main/binary-amd64/Packages",
+		"main/i18n/Translation-en")
+	mirror := NewMirror(root, "amd64", []string{"bookworm"},
+		[]string{MainComponent})
+	model, err := NewModelFromSources(mirror)
+	if err != nil || len(model.Warnings) > 0 {
+		t.Fatalf("unexpected errors: %v %v", err, model.Warnings)
+	}
+	if deb := model.Debs["pkg1"]; deb == nil || deb.LongDesc.IsEmpty() {
+		t.Errorf("expected pkg1 with a long description, got %v", deb)
+	}
+}
+
+func TestMirrorUnlistedTranslation(t *testing.T) {
+	root := writeMirror(t, "main/binary-amd64/Packages")
+	translation := filepath.Join(root, "dists", "bookworm", "main",
+		"i18n", "Translation-en")
+	if err := os.MkdirAll(filepath.Dir(translation), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, translation, []byte("Package: pkg1\n"))
+	mirror := NewMirror(root, "amd64", []string{"bookworm"},
+		[]string{MainComponent})
+	model, err := NewModelFromSources(mirror)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(model.Debs) != 3 {
+		t.Errorf("expected 3 packages, got %d", len(model.Debs))
+	}
+	// reported once, as a failure to read descriptions
+	if len(model.Warnings) != 1 || !model.Warnings[0].Descriptions ||
+		!errors.Is(model.Warnings[0], Err104) {
+		t.Errorf("expected one Err104 descriptions warning, got %v",
+			model.Warnings)
+	}
+}
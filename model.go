@@ -3,18 +3,132 @@
 
 package debsearch
 
+import (
+	"context"
+	"slices"
+
+	"github.com/mark-summerfield/gong"
+)
+
 type Model struct {
-	Debs              map[string]*deb
-	SectionsAndCounts map[string]int
-	TagsAndCounts     map[string]int
+	Debs                map[string]*Deb
+	SectionsAndCounts   map[string]int
+	TagsAndCounts       map[string]int
+	ComponentsAndCounts map[string]int
+	KindsAndCounts      map[string]int    // keyed by Kind.String()
+	Warnings            []*LoadError      // sources that were only partly read
+	versions            map[string][]*Deb // of names read more than once
 }
 
 func newModel() Model {
-	return Model{Debs: map[string]*deb{},
-		SectionsAndCounts: map[string]int{},
-		TagsAndCounts:     map[string]int{}}
+	return Model{Debs: map[string]*Deb{},
+		SectionsAndCounts:   map[string]int{},
+		TagsAndCounts:       map[string]int{},
+		ComponentsAndCounts: map[string]int{},
+		KindsAndCounts:      map[string]int{}}
 }
 
 func NewModel(filepairs ...FilePair) (Model, error) {
-	return parse(filepairs...)
+	sources := make([]Source, 0, len(filepairs))
+	for _, pair := range filepairs {
+		sources = append(sources, pair)
+	}
+	return parse(context.Background(), LoadOptions{}, sources...)
+}
+
+// NewModelFromSources returns a model of the packages from any mix of
+// sources, e.g., apt lists, a mirror, a dpkg status file, or .deb files.
+// If more than one source has a package of the same name, the one read
+// last is in Debs (see [Model.Versions] for all of them). See [LoadError]
+// for how failures are reported.
+func NewModelFromSources(sources ...Source) (Model, error) {
+	return parse(context.Background(), LoadOptions{}, sources...)
+}
+
+// NewModelContext is like [NewModelFromSources] but stops early (returning
+// ctx.Err()) if ctx is cancelled, reads at most options.Workers sources
+// (and parses at most options.Workers file chunks) at once, and reports
+// progress to options.Progress (if not nil).
+func NewModelContext(ctx context.Context, options LoadOptions,
+	sources ...Source) (Model, error) {
+	return parse(ctx, options, sources...)
+}
+
+// Counts returns how many of the model's packages are in each section,
+// have each tag, are in each component, and are of each kind, counting
+// only those for which filter returns true (or all of them if filter is
+// nil), e.g., model.Counts(query.Match).
+func (me *Model) Counts(filter func(deb *Deb) bool) (map[string]int,
+	map[string]int, map[string]int, map[string]int) {
+	sectionsAndCounts := map[string]int{}
+	tagsAndCounts := map[string]int{}
+	componentsAndCounts := map[string]int{}
+	kindsAndCounts := map[string]int{}
+	for _, deb := range me.Debs {
+		if filter == nil || filter(deb) {
+			addCounts(deb, sectionsAndCounts, tagsAndCounts,
+				componentsAndCounts, kindsAndCounts)
+		}
+	}
+	return sectionsAndCounts, tagsAndCounts, componentsAndCounts,
+		kindsAndCounts
+}
+
+// Versions returns every package of the given name that was read, in the
+// order read (so the last is the one in Debs), e.g., a package's version
+// in each suite and for each arc.
+func (me *Model) Versions(name string) []*Deb {
+	if debs, ok := me.versions[name]; ok {
+		return debs
+	}
+	if deb, ok := me.Debs[name]; ok {
+		return []*Deb{deb}
+	}
+	return nil
+}
+
+// VersionsFor returns the packages of the given name that are for the arc
+// (or for any arc), the highest version first.
+func (me *Model) VersionsFor(name, arc string) []*Deb {
+	var debs []*Deb
+	for _, deb := range me.Versions(name) {
+		if deb.Arch == arc || deb.Arch == "all" || deb.Arch == "" {
+			debs = append(debs, deb)
+		}
+	}
+	slices.SortStableFunc(debs, func(a, b *Deb) int {
+		return CompareVersions(b.Version, a.Version)
+	})
+	return debs
+}
+
+// HasArc returns true if any of the packages read (including those
+// replaced in Debs by another of the same name) are for the arc.
+func (me *Model) HasArc(arc string) bool {
+	for _, deb := range me.Debs {
+		if deb.Arch == arc {
+			return true
+		}
+	}
+	for _, debs := range me.versions {
+		for _, deb := range debs {
+			if deb.Arch == arc {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// TagsByFacet returns the model's tags (full names) grouped by facet.
+func (me *Model) TagsByFacet() map[string][]string {
+	return TagsByFacet(gong.SortedMapKeys(me.TagsAndCounts))
+}
+
+func addCounts(deb *Deb, sectionsAndCounts, tagsAndCounts,
+	componentsAndCounts, kindsAndCounts map[string]int) {
+	sectionsAndCounts[deb.Section]++
+	componentsAndCounts[deb.Component]++
+	kindsAndCounts[deb.Kind().String()]++
+	deb.Tags.each(func(tag string) { tagsAndCounts[tag]++ })
 }
@@ -0,0 +1,246 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"bufio"
+	"context"
+	"debug/elf"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ElfNeeds is what an ELF binary or shared object needs to run: its
+// DT_NEEDED sonames (in the order given) and its Debian architecture
+// (e.g., "amd64"; "" if it isn't one Debian supports).
+type ElfNeeds struct {
+	Arc     string
+	Sonames []string
+}
+
+// ReadElfNeeds returns the sonames and architecture of the given ELF
+// binary or shared object.
+func ReadElfNeeds(filename string) (ElfNeeds, error) {
+	file, err := elf.Open(filename)
+	if err != nil {
+		var formatErr *elf.FormatError
+		if errors.As(err, &formatErr) { // doesn't include the filename
+			return ElfNeeds{}, fmt.Errorf("%w: %s: %s", Err114, filename,
+				err)
+		}
+		return ElfNeeds{}, fmt.Errorf("%w: %s", Err114, err)
+	}
+	defer file.Close()
+	sonames, err := file.ImportedLibraries()
+	if err != nil {
+		return ElfNeeds{}, fmt.Errorf("%w: %s: %s", Err114, filename, err)
+	}
+	return ElfNeeds{Arc: elfArc(file), Sonames: sonames}, nil
+}
+
+// elfArc returns the Debian architecture of the ELF file's machine.
+func elfArc(file *elf.File) string {
+	little := file.ByteOrder == binary.LittleEndian
+	is64 := file.Class == elf.ELFCLASS64
+	switch file.Machine {
+	case elf.EM_X86_64:
+		if is64 {
+			return "amd64"
+		}
+		return "x32"
+	case elf.EM_386:
+		return "i386"
+	case elf.EM_AARCH64:
+		return "arm64"
+	case elf.EM_ARM: // debug/elf doesn't give the flags for armel
+		return "armhf"
+	case elf.EM_PPC64:
+		if little {
+			return "ppc64el"
+		}
+		return "ppc64"
+	case elf.EM_S390:
+		return "s390x"
+	case elf.EM_RISCV:
+		return "riscv64"
+	case elf.EM_MIPS:
+		switch {
+		case is64 && little:
+			return "mips64el"
+		case little:
+			return "mipsel"
+		}
+	case elf.EM_LOONGARCH:
+		return "loong64"
+	}
+	return ""
+}
+
+// SonameIndex maps sonames, e.g., "libz.so.1", to the names of the
+// packages that provide them.
+type SonameIndex map[string][]string
+
+func (me SonameIndex) add(soname, name string) {
+	if !slices.Contains(me[soname], name) {
+		me[soname] = append(me[soname], name)
+	}
+}
+
+// InfoPathForRoot returns dpkg's info directory for a chroot or an image's
+// root filesystem, e.g., root/var/lib/dpkg/info.
+func InfoPathForRoot(root string) string {
+	return filepath.Join(root, InfoPath)
+}
+
+// ReadShlibs returns the sonames that the installed packages provide
+// according to the *.shlibs and *.symbols files in dpkg's info directory
+// (normally [InfoPath]); multiarch packages' names have their
+// architecture, e.g., "libz1:i386".
+func ReadShlibs(infoDir string) (SonameIndex, error) {
+	index := SonameIndex{}
+	for _, ext := range []string{".shlibs", ".symbols"} {
+		filenames, err := filepath.Glob(filepath.Join(infoDir, "*"+ext))
+		if err != nil {
+			return index, fmt.Errorf("%w: %s", Err115, err)
+		}
+		for _, filename := range filenames {
+			name := strings.TrimSuffix(filepath.Base(filename), ext)
+			add := func(soname string) { index.add(soname, name) }
+			err := readShlibsFile(filename, ext == ".symbols", add)
+			if err != nil {
+				return index, fmt.Errorf("%w: %s", Err115, err)
+			}
+		}
+	}
+	return index, nil
+}
+
+// readShlibsFile calls add with each soname in a shlibs or symbols file.
+func readShlibsFile(filename string, symbols bool,
+	add func(soname string)) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.IndexByte(" \t#|*", line[0]) > -1 {
+			continue // blank, comment, or a symbols file's symbol line
+		}
+		fields := strings.Fields(line)
+		if symbols { // soname main-dependency-template
+			add(fields[0])
+		} else if len(fields) > 2 && !strings.HasSuffix(fields[0], ":") {
+			// library-name version deps (lines with a type, i.e., udeb:,
+			// are skipped)
+			add(fields[0] + ".so." + fields[1])
+			add(fields[0] + "-" + fields[1] + ".so")
+		}
+	}
+	return scanner.Err()
+}
+
+// ReadContents returns which packages provide the given sonames
+// according to a Contents index (e.g., a mirror's
+// dists/stable/main/Contents-amd64.gz, plain or compressed).
+func ReadContents(filename string, sonames []string) (SonameIndex,
+	error) {
+	index := SonameIndex{}
+	reader, err := openIndex(context.Background(), filename)
+	if err != nil {
+		return index, fmt.Errorf("%w: %s", Err116, err)
+	}
+	defer reader.Close()
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), asciiWs)
+		i := strings.LastIndexAny(line, " \t")
+		if i == -1 {
+			continue
+		}
+		soname := path.Base(strings.TrimSpace(line[:i]))
+		if !slices.Contains(sonames, soname) {
+			continue
+		}
+		for _, location := range strings.Split(line[i+1:], ",") {
+			index.add(soname, path.Base(location)) // e.g., libs/libz1
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return index, fmt.Errorf("%w: %s", Err116, err)
+	}
+	return index, nil
+}
+
+// NeededLibrary is one of an ELF file's sonames with the installed
+// packages that provide it and the model's packages that may provide it
+// (the most likely first).
+type NeededLibrary struct {
+	Soname    string
+	Installed []string
+	Debs      []*Deb
+}
+
+// NeededLibraries returns each of the needed sonames with the installed
+// packages that provide it (from installed, e.g., from [ReadShlibs]) and
+// the model's packages that may provide it: those in contents (e.g., from
+// [ReadContents]) and those named as Debian names library packages. Either
+// index may be nil.
+func (me *Model) NeededLibraries(needs ElfNeeds, installed,
+	contents SonameIndex) []NeededLibrary {
+	libraries := make([]NeededLibrary, 0, len(needs.Sonames))
+	for _, soname := range needs.Sonames {
+		library := NeededLibrary{Soname: soname,
+			Installed: installed[soname]}
+		for _, name := range append(slices.Clone(contents[soname]),
+			sonamePackageNames(soname)...) {
+			if deb, ok := me.Debs[name]; ok &&
+				!slices.Contains(library.Debs, deb) {
+				library.Debs = append(library.Debs, deb)
+			}
+		}
+		libraries = append(libraries, library)
+	}
+	return libraries
+}
+
+// glibcSonames are provided by libc6 rather than by packages named for
+// them.
+var glibcSonames = []string{"libc.so.6", "libm.so.6", "libpthread.so.0",
+	"libdl.so.2", "librt.so.1", "libresolv.so.2", "libutil.so.1",
+	"libanl.so.1", "libnsl.so.1"}
+
+// sonamePackageNames returns the names that Debian policy (8.1) gives
+// the package of a library with the given soname: "libfoo.so.1" is in
+// libfoo1, "libfoo-2.0.so.0" is in libfoo-2.0-0, and "libfoo-2.0.so" is
+// in libfoo-2.0; 64-bit time_t transition names (e.g., libfoo1t64) are
+// also given.
+func sonamePackageNames(soname string) []string {
+	if slices.Contains(glibcSonames, soname) ||
+		strings.HasPrefix(soname, "ld-linux") {
+		return []string{"libc6"}
+	}
+	name, version, found := strings.Cut(soname, ".so.")
+	if !found {
+		base, ok := strings.CutSuffix(soname, ".so")
+		i := strings.LastIndexByte(base, '-')
+		if !ok || i == -1 || i+1 == len(base) || !isDigit(base[i+1]) {
+			return nil // unversioned
+		}
+		name = base
+	} else if name != "" && isDigit(name[len(name)-1]) {
+		name += "-"
+	}
+	name = debianName(name + version) // e.g., libgcc_s → libgcc-s
+	return []string{name, name + "t64"}
+}
@@ -0,0 +1,89 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSonamePackageNames(t *testing.T) {
+	for _, test := range []struct {
+		soname string
+		want   []string
+	}{
+		{"libz.so.1", []string{"libz1", "libz1t64"}},
+		{"libssl.so.3", []string{"libssl3", "libssl3t64"}},
+		{"libgtk-3.so.0", []string{"libgtk-3-0", "libgtk-3-0t64"}},
+		{"libgcc_s.so.1", []string{"libgcc-s1", "libgcc-s1t64"}},
+		{"libfoo-2.0.so", []string{"libfoo-2.0", "libfoo-2.0t64"}},
+		{"libm.so.6", []string{"libc6"}},
+		{"ld-linux-x86-64.so.2", []string{"libc6"}},
+		{"libplugin.so", nil},
+	} {
+		if got := sonamePackageNames(test.soname); !reflect.DeepEqual(got,
+			test.want) {
+			t.Errorf("%s: expected %v, got %v", test.soname, test.want, got)
+		}
+	}
+}
+
+func TestReadShlibs(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "zlib1g:amd64.shlibs"),
+		[]byte("libz 1 zlib1g (>= 1:1.2.3.3)\n"+
+			"udeb: libz 1 zlib1g-udeb (>= 1:1.2.3.3)\n"))
+	writeFile(t, filepath.Join(dir, "libssl3:amd64.symbols"),
+		[]byte("libssl.so.3 libssl3 #MINVER#\n"+
+			"* Build-Depends-Package: libssl-dev\n"+
+			" SSL_accept@OPENSSL_3.0.0 3.0.0\n"))
+	index, err := ReadShlibs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (SonameIndex{"libz.so.1": {"zlib1g:amd64"},
+		"libz-1.so":   {"zlib1g:amd64"},
+		"libssl.so.3": {"libssl3:amd64"}}); !reflect.DeepEqual(index,
+		want) {
+		t.Errorf("expected %v, got %v", want, index)
+	}
+}
+
+func TestNeededLibraries(t *testing.T) {
+	model := modelOf(t, `Package: libz1
+
+Package: libssl3t64
+
+Package: libc6
+
+Package: libcrypto-extras`)
+	contentsFile := filepath.Join(t.TempDir(), "Contents-amd64")
+	writeFile(t, contentsFile, []byte(
+		"usr/lib/x86_64-linux-gnu/libcrypto.so.3    libs/libcrypto-extras\n"+
+			"usr/lib/x86_64-linux-gnu/libother.so.1     libs/libother1\n"))
+	needs := ElfNeeds{Sonames: []string{"libz.so.1", "libssl.so.3",
+		"libcrypto.so.3", "libc.so.6", "libgone.so.2"}, Arc: "amd64"}
+	contents, err := ReadContents(contentsFile, needs.Sonames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	installed := SonameIndex{"libz.so.1": {"zlib1g:amd64"}}
+	var got []string
+	for _, library := range model.NeededLibraries(needs, installed,
+		contents) {
+		names := strings.Join(library.Installed, ",") + "|"
+		for _, deb := range library.Debs {
+			names += deb.Name + " "
+		}
+		got = append(got, library.Soname+"="+strings.TrimSpace(names))
+	}
+	want := []string{"libz.so.1=zlib1g:amd64|libz1",
+		"libssl.so.3=|libssl3t64", "libcrypto.so.3=|libcrypto-extras",
+		"libc.so.6=|libc6", "libgone.so.2=|"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
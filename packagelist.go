@@ -0,0 +1,283 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// PackageRef is a package named in a provisioning file, e.g., "curl" or
+// "curl=7.88.1-10" (a pin); Arch is only set if the name was qualified,
+// e.g., "libc6:i386".
+type PackageRef struct {
+	Name    string
+	Version string // "" if unpinned; may end with "*" as apt allows
+	Arch    string
+	Line    int // 1-based
+}
+
+func (me PackageRef) String() string {
+	text := me.Name
+	if me.Arch != "" {
+		text += ":" + me.Arch
+	}
+	if me.Version != "" {
+		text += "=" + me.Version
+	}
+	return text
+}
+
+// ReadPackageList returns the packages named in a provisioning file:
+// Dockerfiles' (Dockerfile* or *.dockerfile) apt-get install commands,
+// Ansible (*.yml or *.yaml) apt tasks' names, or otherwise either
+// dpkg --get-selections output or a plain list of names ("#" starts a
+// comment).
+func ReadPackageList(filename string) ([]PackageRef, error) {
+	raw, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", Err117, err)
+	}
+	text := strings.ReplaceAll(string(raw), "\r\n", "\n")
+	base := filepath.Base(filename)
+	switch ext := strings.ToLower(filepath.Ext(base)); {
+	case strings.HasPrefix(base, "Dockerfile") || ext == ".dockerfile":
+		return readDockerfile(text), nil
+	case ext == ".yml" || ext == ".yaml":
+		return readAnsible(text), nil
+	}
+	return readPlainList(text), nil
+}
+
+// newPackageRef returns the ref for an apt-style name, e.g.,
+// "libc6:i386=2.36-9"; it returns false for anything that isn't a valid
+// package name.
+func newPackageRef(text string, line int) (PackageRef, bool) {
+	text = strings.Trim(text, `"'`)
+	name, version, _ := strings.Cut(text, "=")
+	name, arch, _ := strings.Cut(name, ":")
+	if !packageNameRx.MatchString(name) {
+		return PackageRef{}, false
+	}
+	return PackageRef{Name: name, Version: version, Arch: arch,
+		Line: line}, true
+}
+
+var packageNameRx = regexp.MustCompile(`^[a-z0-9][a-z0-9.+-]+$`)
+
+var selectionStates = []string{"install", "hold", "deinstall", "purge"}
+
+// readPlainList reads whitespace-separated names or dpkg --get-selections
+// lines (of which only those to install or hold are kept).
+func readPlainList(text string) []PackageRef {
+	var refs []PackageRef
+	for i, line := range strings.Split(text, "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		fields := strings.Fields(line)
+		if len(fields) == 2 && isSelectionState(fields[1]) {
+			if fields[1] == "install" || fields[1] == "hold" {
+				if ref, ok := newPackageRef(fields[0], i+1); ok {
+					refs = append(refs, ref)
+				}
+			}
+			continue
+		}
+		for _, field := range fields {
+			if ref, ok := newPackageRef(field, i+1); ok {
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs
+}
+
+func isSelectionState(field string) bool {
+	for _, state := range selectionStates {
+		if field == state {
+			return true
+		}
+	}
+	return false
+}
+
+// aptValuedOption matches the apt options that take a value, e.g., -t
+// bookworm-backports; a short option may end a group of flags, e.g., -yt.
+const aptValuedOption = `(?:--(?:target-release|default-release|option|` +
+	`config-file)|-[a-zA-Z]*[toc])`
+
+var (
+	aptInstallRx = regexp.MustCompile(`\bapt(?:-get)?\s+(?:` +
+		aptValuedOption + `\s+\S+\s+|-\S+\s+)*install\b`)
+	aptValuedRx = regexp.MustCompile(`^` + aptValuedOption + `$`)
+)
+
+// readDockerfile reads the names given to apt-get install (or apt
+// install) in RUN instructions (including continuation lines); each
+// ref's Line is that of its RUN instruction.
+func readDockerfile(text string) []PackageRef {
+	var refs []PackageRef
+	lines := strings.Split(text, "\n")
+	for i := 0; i < len(lines); i++ {
+		start := i
+		command := strings.TrimSpace(lines[i])
+		for strings.HasSuffix(command, `\`) && i+1 < len(lines) {
+			i++
+			command = strings.TrimSuffix(command, `\`) + " " +
+				strings.TrimSpace(lines[i])
+		}
+		if !strings.HasPrefix(strings.ToUpper(command), "RUN ") {
+			continue
+		}
+		for _, loc := range aptInstallRx.FindAllStringIndex(command, -1) {
+			refs = append(refs, shellArgs(command[loc[1]:], start+1)...)
+		}
+	}
+	return refs
+}
+
+// shellArgs returns the refs among the words of a shell command up to the
+// end of the command (e.g., && or ;), skipping options and the values of
+// those that take one (e.g., -t bookworm-backports).
+func shellArgs(command string, line int) []PackageRef {
+	var refs []PackageRef
+	skip := false // the word is an option's value
+	for _, word := range strings.Fields(command) {
+		if strings.ContainsAny(word[:1], "&|;>)") {
+			break
+		}
+		end := strings.HasSuffix(word, ";")
+		word = strings.TrimSuffix(word, ";")
+		switch {
+		case skip:
+			skip = false
+		case strings.HasPrefix(word, "-"):
+			skip = aptValuedRx.MatchString(word)
+		default:
+			if ref, ok := newPackageRef(word, line); ok {
+				refs = append(refs, ref)
+			}
+		}
+		if end {
+			break
+		}
+	}
+	return refs
+}
+
+var (
+	ansibleAptRx = regexp.MustCompile(
+		`^(\s*(?:-\s+)?)(?:ansible\.builtin\.)?apt:\s*(.*)$`)
+	ansibleKeyRx  = regexp.MustCompile(`^(\s*(?:-\s+)?)([a-z_]+):\s*(.*)$`)
+	ansibleItemRx = regexp.MustCompile(`^\s*-\s+(.+)$`)
+)
+
+// readAnsible reads the names from apt tasks, given inline (apt:
+// name=curl,git) or as the task's name (or pkg) key's value, list, or
+// block list; templated names (e.g., "{{ item }}") are taken from the
+// task's loop or with_items list.
+func readAnsible(text string) []PackageRef {
+	var refs []PackageRef
+	lines := strings.Split(text, "\n")
+	for i := 0; i < len(lines); i++ {
+		match := ansibleAptRx.FindStringSubmatch(lines[i])
+		if match == nil {
+			continue
+		}
+		if rest := strings.TrimSpace(match[2]); rest != "" {
+			for _, pair := range strings.Fields(rest) { // name=a,b
+				key, value, _ := strings.Cut(pair, "=")
+				if key == "name" || key == "pkg" {
+					refs = append(refs, ansibleNames(value, i+1)...)
+				}
+			}
+			continue
+		}
+		apt, indent := i, len(match[1])
+		for i+1 < len(lines) && ansibleIndent(lines[i+1]) > indent {
+			i++
+			keyMatch := ansibleKeyRx.FindStringSubmatch(lines[i])
+			if keyMatch == nil || (keyMatch[2] != "name" &&
+				keyMatch[2] != "pkg") {
+				continue
+			}
+			if value := strings.TrimSpace(keyMatch[3]); value != "" {
+				if strings.Contains(value, "{{") {
+					refs = append(refs, ansibleLoop(lines, apt, indent)...)
+				} else {
+					refs = append(refs, ansibleNames(value, i+1)...)
+				}
+				continue
+			}
+			refs = append(refs, ansibleItems(lines, &i, len(keyMatch[1]))...)
+		}
+	}
+	return refs
+}
+
+// ansibleItems returns the refs in the block list that follows line *i
+// (whose key is at the given indent) and advances *i past it.
+func ansibleItems(lines []string, i *int, indent int) []PackageRef {
+	var refs []PackageRef
+	for *i+1 < len(lines) && ansibleIndent(lines[*i+1]) >= indent {
+		item := ansibleItemRx.FindStringSubmatch(lines[*i+1])
+		if item == nil {
+			break
+		}
+		*i++
+		refs = append(refs, ansibleNames(item[1], *i+1)...)
+	}
+	return refs
+}
+
+// ansibleNames returns the refs in a scalar, comma-separated, or inline
+// list value, e.g., "curl", "curl,git", or "[curl, git]".
+func ansibleNames(value string, line int) []PackageRef {
+	var refs []PackageRef
+	value, _, _ = strings.Cut(value, " #")
+	value = strings.Trim(strings.TrimSpace(value), "[]")
+	for _, name := range strings.Split(value, ",") {
+		if ref, ok := newPackageRef(strings.TrimSpace(name), line); ok {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// ansibleLoop returns the refs in the loop or with_items list of the task
+// whose apt: key is on the given line at the given indent.
+func ansibleLoop(lines []string, apt, indent int) []PackageRef {
+	first := apt // the task's first line, e.g., "- name: ..."
+	for first > 0 && !strings.HasPrefix(strings.TrimSpace(lines[first]),
+		"- ") && ansibleIndent(lines[first-1]) >= indent-2 {
+		first--
+	}
+	for i := first; i < len(lines); i++ {
+		if i > first && ansibleIndent(lines[i]) < indent {
+			break // end of task
+		}
+		match := ansibleKeyRx.FindStringSubmatch(lines[i])
+		if match == nil || len(match[1]) != indent ||
+			(match[2] != "loop" && match[2] != "with_items") {
+			continue
+		}
+		if value := strings.TrimSpace(match[3]); value != "" {
+			return ansibleNames(value, i+1)
+		}
+		return ansibleItems(lines, &i, indent)
+	}
+	return nil
+}
+
+// ansibleIndent returns the line's indentation or a huge indent if it is
+// blank or a comment (so that such lines don't end a block).
+func ansibleIndent(line string) int {
+	trimmed := strings.TrimLeft(line, " ")
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return 1 << 30
+	}
+	return len(line) - len(trimmed)
+}
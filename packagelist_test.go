@@ -0,0 +1,76 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadPackageList(t *testing.T) {
+	dir := t.TempDir()
+	for _, test := range []struct {
+		name string
+		text string
+		want []string // each ref as NAME[:ARCH][=VERSION]@LINE
+	}{
+		{"packages.txt", "curl git # tools\n\nlibc6:i386=2.36-9 Bad_Name\n",
+			[]string{"curl@1", "git@1", "libc6:i386=2.36-9@3"}},
+		{"selections", "curl\t\t\tinstall\nold\t\t\tdeinstall\n" +
+			"vim\t\t\thold\n",
+			[]string{"curl@1", "vim@3"}},
+		{"Dockerfile", `FROM debian:bookworm
+RUN apt-get update && apt-get install -y --no-install-recommends \
+    curl \
+    ca-certificates && rm -rf /var/lib/apt/lists/*
+RUN apt-get -o Dpkg::Options::=--force-confnew install -y vim
+RUN apt-get install -t bookworm-backports -y golang-go; echo done
+RUN apt -yt bookworm-backports install hello libc6:i386=2.36*
+RUN apt-get -c /etc/apt/x.conf --option Acquire::Retries=3 install zsh
+RUN apt-get --target-release=bookworm-backports install -y jq
+`,
+			[]string{"curl@2", "ca-certificates@2", "vim@5",
+				"golang-go@6", "hello@7", "libc6:i386=2.36*@7", "zsh@8",
+				"jq@9"}},
+		{"site.yml", `- hosts: all
+  tasks:
+    - name: Install tools
+      apt: name=curl,git state=present
+    - name: Install editors
+      ansible.builtin.apt:
+        name:
+          - vim
+          - emacs-nox # smaller
+        state: present
+    - name: Install one
+      apt:
+        pkg: [zsh, "fish"]
+    - name: Install looped
+      apt:
+        name: "{{ item }}"
+      loop:
+        - htop
+        - libc6:i386
+`,
+			[]string{"curl@4", "git@4", "vim@8", "emacs-nox@9",
+				"zsh@13", "fish@13", "htop@18", "libc6:i386@19"}},
+	} {
+		filename := filepath.Join(dir, test.name)
+		writeFile(t, filename, []byte(test.text))
+		refs, err := ReadPackageList(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, ref := range refs {
+			got = append(got, fmt.Sprintf("%s@%d", ref, ref.Line))
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s:\nexpected %v\n     got %v", test.name,
+				test.want, got)
+		}
+	}
+}
@@ -4,233 +4,515 @@
 package debsearch
 
 import (
-	"bufio"
+	"bytes"
+	"context"
 	"errors"
-	"fmt"
 	"io"
-	"os"
+	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
 
+	"github.com/mark-summerfield/debsearch/deb822"
 	"github.com/mark-summerfield/gong"
 )
 
+// Each task only writes its own source's slot in debs, descs, and errs,
+// so these need no locking; they are merged into a model in source order
+// once every task is done.
 type parser struct {
-	model                Model
-	modelMutex           sync.Mutex
-	err                  error
-	errMutex             sync.Mutex
-	descForPackages      map[string]string
-	descForPackagesMutex sync.Mutex
+	debs          [][]*Deb
+	descs         []sourceDescs
+	errs          []*LoadError // indexed by task
+	okCount       atomic.Int64 // sources whose packages were read
+	stats         *loadStats
+	options       LoadOptions
+	progressMutex sync.Mutex
+	sourcesDone   int
 }
 
-func parse(filepairs ...FilePair) (Model, error) {
-	if len(filepairs) == 0 {
+type sourceDescs struct {
+	names     []string
+	longDescs []Description
+}
+
+// A task is one of a source's two reads (debs or descriptions).
+type task struct {
+	index    int
+	source   Source
+	forDescs bool
+}
+
+type sourceState struct {
+	start   time.Time
+	pending int
+}
+
+func parse(ctx context.Context, options LoadOptions,
+	sources ...Source) (Model, error) {
+	if len(sources) == 0 {
 		return Model{}, Err102
 	}
-	parser := &parser{model: newModel(),
-		descForPackages: map[string]string{}}
-	return parser.parse(filepairs...)
+	if options.Workers <= 0 {
+		options.Workers = runtime.NumCPU()
+	}
+	parser := &parser{stats: &loadStats{}, options: options}
+	ctx = context.WithValue(ctx, loadStatsKey{}, parser.stats)
+	ctx = context.WithValue(ctx, chunkSlotsKey{},
+		make(chunkSlots, options.Workers))
+	return parser.parse(ctx, sources...)
 }
 
-func (me *parser) parse(filepairs ...FilePair) (Model, error) {
+func (me *parser) parse(ctx context.Context, sources ...Source) (Model,
+	error) {
+	states := make([]sourceState, len(sources))
+	me.debs = make([][]*Deb, len(sources))
+	me.descs = make([]sourceDescs, len(sources))
+	me.errs = make([]*LoadError, 2*len(sources))
+	tasks := make(chan task, 2*len(sources))
+	for i, source := range sources {
+		states[i].pending = 2
+		tasks <- task{i, source, false}
+		tasks <- task{i, source, true}
+	}
+	close(tasks)
 	var wg sync.WaitGroup
-	for i, pair := range filepairs {
+	for i := 0; i < min(me.options.Workers, 2*len(sources)); i++ {
 		wg.Add(1)
-		go func(i int, pair FilePair) {
+		go func() {
 			defer wg.Done()
-			me.readPackages(pair.Packages)
-		}(i, pair)
-		if pair.I18n != "" {
-			wg.Add(1)
-			go func(i int, pair FilePair) {
-				defer wg.Done()
-				me.readDescriptions(pair.I18n)
-			}(i, pair)
-		}
+			for task := range tasks {
+				if ctx.Err() != nil {
+					return
+				}
+				me.progressMutex.Lock()
+				if states[task.index].start.IsZero() {
+					states[task.index].start = time.Now()
+				}
+				me.progressMutex.Unlock()
+				var err error
+				if task.forDescs {
+					err = me.readDescriptions(ctx, task)
+				} else {
+					err = me.readDebs(ctx, task)
+				}
+				if err != nil {
+					slot := 2 * task.index
+					if task.forDescs {
+						slot++
+					}
+					me.errs[slot] = &LoadError{Origin: task.source.Origin(),
+						Descriptions: task.forDescs, Err: err}
+				}
+				me.taskDone(task, states, len(sources))
+			}
+		}()
 	}
 	wg.Wait()
-	for name, longDesc := range me.descForPackages { // merge
-		if deb, ok := me.model.Debs[name]; ok {
-			deb.LongDesc = longDesc
+	if err := ctx.Err(); err != nil {
+		return Model{}, err
+	}
+	model := me.merge()
+	if me.okCount.Load() == 0 && len(model.Debs) == 0 { // fatal
+		errs := []error{Err108}
+		for _, err := range model.Warnings {
+			errs = append(errs, err)
 		}
+		return Model{}, errors.Join(errs...)
 	}
-	return me.model, me.err
+	return model, nil
 }
 
-func (me *parser) readPackages(filename string) {
-	model, err := readPackages(filename)
-	if err != nil {
-		me.errMutex.Lock()
-		defer me.errMutex.Unlock()
-		me.err = errors.Join(err)
-	} else {
-		me.modelMutex.Lock()
-		defer me.modelMutex.Unlock()
-		for name, deb := range model.Debs {
-			me.model.Debs[name] = deb
+// merge returns a model of every source's packages (in source order, so
+// later sources' packages replace earlier ones of the same name, although
+// all are kept as versions) with their long descriptions and the failures
+// as warnings.
+func (me *parser) merge() Model {
+	size := 0
+	for _, debs := range me.debs {
+		size += len(debs)
+	}
+	model := newModel()
+	model.Debs = make(map[string]*Deb, size)
+	model.versions = map[string][]*Deb{}
+	for _, debs := range me.debs {
+		for _, deb := range debs {
+			if old, ok := model.Debs[deb.Name]; ok {
+				versions, ok := model.versions[deb.Name]
+				if !ok {
+					versions = []*Deb{old}
+				}
+				model.versions[deb.Name] = append(versions, deb)
+			}
+			model.Debs[deb.Name] = deb
 		}
-		for section, count := range model.SectionsAndCounts {
-			me.model.SectionsAndCounts[section] += count
+	}
+	// counted from the final set so replaced duplicates aren't counted
+	model.SectionsAndCounts, model.TagsAndCounts,
+		model.ComponentsAndCounts, model.KindsAndCounts = model.Counts(nil)
+	for _, descs := range me.descs {
+		for i, name := range descs.names {
+			if deb, ok := model.Debs[name]; ok {
+				deb.LongDesc = descs.longDescs[i]
+			}
 		}
-		for tag, count := range model.TagsAndCounts {
-			me.model.TagsAndCounts[tag] += count
+	}
+	for _, err := range me.errs { // in source order
+		if err != nil {
+			model.Warnings = append(model.Warnings, err)
 		}
 	}
+	return model
 }
 
-func (me *parser) readDescriptions(filename string) {
-	if descForPackages := readDescriptions(filename); len(
-		descForPackages) > 0 {
-		me.descForPackagesMutex.Lock()
-		defer me.descForPackagesMutex.Unlock()
-		for name, desc := range descForPackages {
-			me.descForPackages[name] = desc
+func (me *parser) taskDone(task task, states []sourceState, total int) {
+	me.progressMutex.Lock()
+	defer me.progressMutex.Unlock()
+	state := &states[task.index]
+	if state.pending--; state.pending == 0 {
+		me.sourcesDone++
+		if me.options.Progress != nil {
+			me.options.Progress(Progress{Origin: task.source.Origin(),
+				Elapsed: time.Since(state.start), SourcesDone: me.sourcesDone,
+				SourcesTotal: total, BytesRead: me.stats.bytesRead.Load(),
+				PackagesParsed: int(me.stats.packagesParsed.Load())})
 		}
 	}
 }
 
-func readPackages(filename string) (Model, error) {
-	model := newModel()
-	file, err := os.Open(filename)
-	if err != nil {
-		return model, fmt.Errorf("%w: %s", Err101, err)
-	}
-	defer file.Close()
-	state := &parseState{}
-	deb := NewDeb()
-	reader := bufio.NewReader(file)
-	for {
-		line, err := reader.ReadString('\n')
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			return model, err
+// readDebs keeps whatever packages the source provided, even if it
+// failed part way, and returns the source's error (if any).
+func (me *parser) readDebs(ctx context.Context, task task) error {
+	var debs []*Deb
+	err := task.source.ReadDebs(ctx, func(deb *Deb) {
+		debs = append(debs, deb)
+		me.stats.packagesParsed.Add(1)
+	})
+	if err == nil {
+		me.okCount.Add(1)
+	}
+	me.debs[task.index] = debs
+	return err
+}
+
+func (me *parser) readDescriptions(ctx context.Context, task task) error {
+	descs := &me.descs[task.index]
+	return task.source.ReadDescriptions(ctx, func(name string,
+		longDesc Description) {
+		descs.names = append(descs.names, name)
+		descs.longDescs = append(descs.longDescs, longDesc)
+	})
+}
+
+// readText returns all of file's data as a string that shares the data's
+// memory, so that the fields parsed from it are substrings rather than
+// copies.
+func readText(file io.Reader) (string, error) {
+	var buffer bytes.Buffer
+	if sized, ok := file.(interface{ sizeHint() int }); ok {
+		buffer.Grow(sized.sizeHint() + bytes.MinRead)
+	}
+	if _, err := buffer.ReadFrom(file); err != nil || buffer.Len() == 0 {
+		return "", err
+	}
+	data := buffer.Bytes()
+	return unsafe.String(unsafe.SliceData(data), len(data)), nil
+}
+
+// maxChunks is the most chunks that a file is split into for parsing.
+var maxChunks = runtime.NumCPU()
+
+// chunkCount returns how many chunks to split text of the given size into
+// for parsing: small files aren't worth splitting.
+func chunkCount(size int, slots chunkSlots) int {
+	count := max(1, min(maxChunks, size/minChunkSize))
+	if slots != nil {
+		count = min(count, cap(slots))
+	}
+	return count
+}
+
+type chunkSlotsKey struct{}
+
+// chunkSlots limits how many chunks are parsed at once across all of a
+// load's sources to its [LoadOptions] Workers; a nil chunkSlots (e.g.,
+// when a source is read outside of [NewModelContext]) has no limit.
+type chunkSlots chan struct{}
+
+func slotsFor(ctx context.Context) chunkSlots {
+	slots, _ := ctx.Value(chunkSlotsKey{}).(chunkSlots)
+	return slots
+}
+
+func (me chunkSlots) acquire() {
+	if me != nil {
+		me <- struct{}{}
+	}
+}
+
+func (me chunkSlots) release() {
+	if me != nil {
+		<-me
+	}
+}
+
+type debsChunk struct {
+	debs []*Deb
+	err  error
+}
+
+// parseDebs parses the Packages text's stanza-aligned chunks concurrently
+// (within ctx's chunk slots) and then calls add for every valid package in
+// file order. If there is an error, the packages before it are still
+// added.
+func parseDebs(ctx context.Context, text, label string,
+	add func(deb *Deb)) error {
+	slots := slotsFor(ctx)
+	chunks := deb822.Split(text, chunkCount(len(text), slots))
+	results := make([]debsChunk, len(chunks))
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(result *debsChunk, chunk deb822.Chunk) {
+			defer wg.Done()
+			slots.acquire()
+			defer slots.release()
+			interner := newInterner()
+			result.err = chunk.Scan(label,
+				func(fields []deb822.RawField) error {
+					deb := NewDeb()
+					for _, field := range fields {
+						addRawField(deb, field, interner)
+					}
+					if deb.IsValid() {
+						deb.pack()
+						result.debs = append(result.debs, deb)
+					}
+					return nil
+				})
+		}(&results[i], chunk)
+	}
+	wg.Wait()
+	for _, result := range results {
+		for _, deb := range result.debs {
+			add(deb)
 		}
-		if line == "" {
-			state.Clear()
-			continue
+		if result.err != nil {
+			return result.err
 		}
-		if strings.HasPrefix(line, packagePrefix) {
-			state.Clear()
-			if deb.IsValid() {
-				model.Debs[deb.Name] = deb.Copy()
-			}
-			deb.Clear()
-			deb.Name = strings.TrimSpace(line[packagePrefixLen:])
-		} else if strings.HasPrefix(line, " ") {
-			if state.inTags {
-				addTags(deb, line, &model)
-			} else if state.inDesc {
-				deb.LongDesc += getDesc(line)
-			} else {
-				state.Clear()
-			}
-		} else {
-			state.Update(maybeAddKeyValue(deb, line, &model))
+	}
+	return nil
+}
+
+// interner shares the strings that many packages have in common, e.g.,
+// maintainers and versions, so that each is only stored once per chunk;
+// sections and tags are stored once per process in their tables.
+type interner struct {
+	strs   map[string]string
+	tagIDs map[string]int // raw "facet::tag" to its "facet/tag" ID
+}
+
+func newInterner() *interner {
+	return &interner{strs: map[string]string{}, tagIDs: map[string]int{}}
+}
+
+func (me *interner) intern(s string) string {
+	if me == nil {
+		return s
+	}
+	if interned, ok := me.strs[s]; ok {
+		return interned
+	}
+	s = strings.Clone(s) // don't keep the whole text alive for it
+	me.strs[s] = s
+	return s
+}
+
+func (me *interner) tagID(item string) int {
+	if me != nil {
+		if id, ok := me.tagIDs[item]; ok {
+			return id
 		}
 	}
-	if deb.IsValid() {
-		model.Debs[deb.Name] = deb.Copy()
+	id := tagTable.id(strings.ReplaceAll(item, "::", "/"))
+	if me != nil {
+		me.tagIDs[strings.Clone(item)] = id
+	}
+	return id
+}
+
+// addRawField is addField for fields scanned by [deb822.Chunk.Scan].
+func addRawField(deb *Deb, field deb822.RawField, interner *interner) {
+	switch field.Name {
+	case "Description":
+		deb.ShortDesc, deb.LongDesc = splitRawDescription(field.Value)
+	case "Tag":
+		addTags(deb, field.Text(), interner)
+	case "Version", "Architecture", "Priority", "Source", "Maintainer":
+		addField(deb, field.Name, interner.intern(field.Text()))
+	default:
+		addField(deb, field.Name, field.Text())
 	}
-	return model, nil
 }
 
-func addTags(deb *deb, line string, model *Model) {
-	for _, item := range strings.Split(line, ",") {
-		item = strings.TrimSpace(item)
-		if item != "" {
-			item = strings.ReplaceAll(item, "::", "/")
-			deb.Tags.Add(item)
-			model.TagsAndCounts[item]++
+func addTags(deb *Deb, value string, interner *interner) {
+	for value != "" {
+		var item string
+		item, value, _ = strings.Cut(value, ",")
+		if item = strings.TrimSpace(item); item != "" {
+			deb.Tags.addID(interner.tagID(item))
 		}
 	}
 }
 
-func maybeAddKeyValue(deb *deb, line string, model *Model) (bool, bool) {
-	if key, value, found := strings.Cut(line, ":"); found {
-		key = strings.TrimSpace(key)
-		value = strings.TrimSpace(value)
-		if value != "" {
-			switch key {
-			case "Description":
-				deb.ShortDesc = value
-				return false, true
-			case "Homepage":
-				deb.Url = value
-			case "Installed-Size":
-				deb.Size = gong.StrToInt(value, 0)
-			case "Size": // download size
-				if deb.Size == 0 {
-					deb.Size = gong.StrToInt(value, 0)
-				}
-			case "Section":
-				deb.Section = value
-				model.SectionsAndCounts[value]++
-			case "Tag":
-				addTags(deb, value, model)
-				return true, false
-			case "Version":
-				deb.Version = value
-			}
+func addField(deb *Deb, key, value string) {
+	if value == "" {
+		return
+	}
+	switch key {
+	case "Package":
+		deb.Name = value
+	case "Description":
+		var longDesc string
+		deb.ShortDesc, longDesc = splitDescription(value)
+		deb.LongDesc = NewDescription(longDesc)
+	case "Homepage":
+		deb.Url = value
+	case "Installed-Size":
+		deb.Size = gong.StrToInt(value, 0)
+	case "Size": // download size
+		deb.DownloadSize = gong.StrToInt(value, 0)
+		if deb.Size == 0 {
+			deb.Size = deb.DownloadSize
 		}
+	case "Section":
+		component, section := SplitSection(value)
+		deb.Component = sectionTable.intern(component)
+		deb.Section = sectionTable.intern(section)
+	case "Tag":
+		addTags(deb, value, nil)
+	case "Version":
+		deb.Version = value
+	case "Architecture":
+		deb.Arch = value
+	case "Source":
+		deb.Source = value
+	case "Maintainer":
+		deb.Maintainer = value
+	case "Priority":
+		deb.Priority = value
+	case "Depends":
+		deb.Depends = value
+	case "Pre-Depends":
+		deb.PreDepends = value
+	case "Recommends":
+		deb.Recommends = value
+	case "Suggests":
+		deb.Suggests = value
+	case "Conflicts":
+		deb.Conflicts = value
+	case "Breaks":
+		deb.Breaks = value
+	case "Replaces":
+		deb.Replaces = value
+	case "Provides":
+		deb.Provides = value
+	case "Filename":
+		deb.Filename = value
+	case "SHA256":
+		deb.Sha256 = value
 	}
-	return false, false
 }
 
-func readDescriptions(filename string) map[string]string {
-	descForPackage := map[string]string{}
-	file, err := os.Open(filename)
+type descsChunk struct {
+	names     []string
+	longDescs []Description
+	err       error
+}
+
+// readDescriptions parses the Translation text's stanza-aligned chunks
+// concurrently (within ctx's chunk slots) and then calls add for every
+// description in file order.
+func readDescriptions(ctx context.Context, file io.Reader, label string,
+	add func(name string, longDesc Description)) error {
+	text, err := readText(file)
 	if err != nil {
-		return descForPackage
+		return err
 	}
-	defer file.Close()
-	name := ""
-	longDesc := ""
-	reader := bufio.NewReader(file)
-	for {
-		line, err := reader.ReadString('\n')
-		if err != nil {
-			break
-		}
-		if line == "" {
-			continue
+	slots := slotsFor(ctx)
+	chunks := deb822.Split(text, chunkCount(len(text), slots))
+	results := make([]descsChunk, len(chunks))
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(result *descsChunk, chunk deb822.Chunk) {
+			defer wg.Done()
+			slots.acquire()
+			defer slots.release()
+			result.err = chunk.Scan(label,
+				func(fields []deb822.RawField) error {
+					if name, longDesc := descriptionFor(
+						fields); name != "" && !longDesc.IsEmpty() {
+						result.names = append(result.names, name)
+						result.longDescs = append(result.longDescs,
+							longDesc)
+					}
+					return nil
+				})
+		}(&results[i], chunk)
+	}
+	wg.Wait()
+	for _, result := range results {
+		for i, name := range result.names {
+			add(name, result.longDescs[i])
 		}
-		if strings.HasPrefix(line, packagePrefix) {
-			if name != "" && longDesc != "" {
-				descForPackage[name] = longDesc
-			}
-			name = strings.TrimSpace(line[packagePrefixLen:])
-			longDesc = ""
-		} else if strings.HasPrefix(line, " ") {
-			longDesc += getDesc(line)
+		if result.err != nil {
+			return result.err
 		}
 	}
-	if name != "" && longDesc != "" {
-		descForPackage[name] = strings.TrimRight(longDesc, asciiWs)
-	}
-	return descForPackage
+	return nil
 }
 
-func getDesc(line string) string {
-	line = strings.TrimRight(line[1:], asciiWs)
-	if line == "." {
-		line = ""
+// descriptionFor returns the package name and its long description from
+// a Translation stanza's Description-LANG field.
+func descriptionFor(fields []deb822.RawField) (string, Description) {
+	name := ""
+	longDesc := Description{}
+	for _, field := range fields {
+		if field.Name == "Package" {
+			name = field.Text()
+		} else if strings.HasPrefix(field.Name, "Description-") &&
+			field.Name != "Description-md5" && longDesc.IsEmpty() {
+			_, longDesc = splitRawDescription(field.Value)
+			longDesc.text = strings.Clone(longDesc.text) // free the text
+		}
 	}
-	return line + "\n"
-}
-
-type parseState struct {
-	inTags bool
-	inDesc bool
+	return name, longDesc
 }
 
-func (me *parseState) Clear() {
-	me.inTags = false
-	me.inDesc = false
+// splitDescription returns a Description field's short description and
+// its long description with "." lines converted to empty lines.
+func splitDescription(value string) (string, string) {
+	shortDesc, rest, _ := strings.Cut(value, "\n")
+	if rest == "" {
+		return shortDesc, ""
+	}
+	var longDesc strings.Builder
+	for _, line := range strings.Split(rest, "\n") {
+		if line == "." {
+			line = ""
+		}
+		longDesc.WriteString(line)
+		longDesc.WriteByte('\n')
+	}
+	return shortDesc, strings.TrimRight(longDesc.String(), asciiWs)
 }
 
-func (me *parseState) Update(inTags, inDesc bool) {
-	me.inTags = inTags
-	me.inDesc = inDesc
+// splitRawDescription is splitDescription for a raw (scanned) value; the
+// long description is only converted if it is used.
+func splitRawDescription(value string) (string, Description) {
+	shortDesc, rest, _ := strings.Cut(value, "\n")
+	return trimRightWs(shortDesc), rawDescription(rest)
 }
@@ -0,0 +1,366 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/mark-summerfield/debsearch/deb822"
+)
+
+// realCount is about how many packages a Debian release's main amd64
+// Packages file has.
+const realCount = 64_000
+
+var sections = [...]string{"libs", "utils", "devel", "contrib/games",
+	"non-free/libs", "python", "doc"}
+
+// writeFixture writes a Packages file (and, if i18n, a Translation file
+// with the long descriptions instead) of count packages named pkgFIRST on
+// in dir and returns its FilePair. The last stanza repeats pkgFIRST with
+// another version so that the parse order matters.
+func writeFixture(tb testing.TB, dir string, first, count int, arch string,
+	i18n bool) FilePair {
+	tb.Helper()
+	var packages, translation bytes.Buffer
+	for i := first; i < first+count; i++ {
+		writeStanza(&packages, &translation, i, "1.0", arch, i18n)
+	}
+	writeStanza(&packages, &translation, first, "9.0", arch, i18n)
+	pair := FilePair{Packages: filepath.Join(dir,
+		fmt.Sprintf("%s_%d_Packages", arch, first))}
+	writeFile(tb, pair.Packages, packages.Bytes())
+	if i18n {
+		pair.I18n = strings.TrimSuffix(pair.Packages, "Packages") +
+			"Translation-en"
+		writeFile(tb, pair.I18n, translation.Bytes())
+	}
+	return pair
+}
+
+func writeStanza(packages, translation *bytes.Buffer, i int,
+	version, arch string, i18n bool) {
+	fmt.Fprintf(packages, "Package: pkg%d\nSource: src%d\n"+
+		"Version: %s-%d\nInstalled-Size: %d\n"+
+		"Maintainer: Maintainer %d <m%d@example.org>\n"+
+		"Architecture: %s\nDepends: libc6 (>= 2.34), pkg%d | alt%d\n"+
+		"Description: package number %d\n", i, i/3, version, i%7,
+		1+i%5000, i%400, i%400, arch, i+1, i, i)
+	longDesc := packages
+	if i18n {
+		fmt.Fprintf(packages, "Description-md5: %032x\n", i)
+		fmt.Fprintf(translation, "Package: pkg%d\nDescription-md5: %032x\n"+
+			"Description-en: package number %d\n", i, i, i)
+		longDesc = translation
+	}
+	for j := 0; j < 3+i%6; j++ {
+		if j == 2 {
+			longDesc.WriteString(" .\n")
+		}
+		fmt.Fprintf(longDesc, " Line %d of package %d's long description "+
+			"which goes on\n for a while.\n", j, i)
+	}
+	if i18n {
+		translation.WriteString("\n")
+	}
+	fmt.Fprintf(packages, "Homepage: https://example.org/pkg%d\n"+
+		"Tag: role::program, implemented-in::c, suite::debian\n"+
+		"Section: %s\nPriority: optional\n"+
+		"Filename: pool/main/p/pkg%d/pkg%d_%s_%s.deb\nSize: %d\n"+
+		"SHA256: %064x\n\n", i, sections[i%len(sections)], i, i, version,
+		arch, 1000+i, i)
+}
+
+func writeFile(tb testing.TB, filename string, data []byte) {
+	tb.Helper()
+	if err := os.WriteFile(filename, data, 0o644); err != nil {
+		tb.Fatal(err)
+	}
+}
+
+// withMaxChunks runs fn with files split into at most n chunks.
+func withMaxChunks(n int, fn func()) {
+	old := maxChunks
+	maxChunks = n
+	defer func() { maxChunks = old }()
+	fn()
+}
+
+// newModelOrFail reads the pairs with as many workers as chunks so that
+// files are chunked however many CPUs there are.
+func newModelOrFail(tb testing.TB, pairs ...FilePair) Model {
+	tb.Helper()
+	sources := make([]Source, 0, len(pairs))
+	for _, pair := range pairs {
+		sources = append(sources, pair)
+	}
+	model, err := NewModelContext(context.Background(),
+		LoadOptions{Workers: maxChunks}, sources...)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if len(model.Warnings) > 0 {
+		tb.Fatalf("unexpected warnings: %v", model.Warnings)
+	}
+	return model
+}
+
+// modelOf returns a model of the packages in the Packages text; each
+// stanza only needs the fields that differ from the defaults, i.e.,
+// Package, and any of Version, Section, Architecture, Description, and
+// relations.
+func modelOf(tb testing.TB, packages string) Model {
+	tb.Helper()
+	var text strings.Builder
+	for _, stanza := range strings.Split(strings.TrimSpace(packages),
+		"\n\n") {
+		text.WriteString(stanza)
+		for _, field := range []string{"Version: 1.0-1",
+			"Installed-Size: 100", "Section: misc",
+			"Architecture: amd64", "Description: a package"} {
+			name, _, _ := strings.Cut(field, ":")
+			if !strings.Contains("\n"+stanza, "\n"+name+":") {
+				text.WriteString("\n" + field)
+			}
+		}
+		text.WriteString("\n\n")
+	}
+	return newModelOrFail(tb, NewReaderPair("test",
+		strings.NewReader(text.String()), nil))
+}
+
+func TestChunkedParse(t *testing.T) {
+	dir := t.TempDir()
+	pairs := []FilePair{writeFixture(t, dir, 0, 8000, "amd64", false),
+		writeFixture(t, dir, 8000, 8000, "amd64", true)}
+	for _, pair := range pairs {
+		if info, err := os.Stat(pair.Packages); err != nil ||
+			info.Size() < 8*minChunkSize {
+			t.Fatalf("%s is too small to be chunked", pair.Packages)
+		}
+	}
+	var single Model
+	withMaxChunks(1, func() { single = newModelOrFail(t, pairs...) })
+	if len(single.Debs) != 16000 {
+		t.Fatalf("expected 16000 packages, got %d", len(single.Debs))
+	}
+	for _, n := range []int{2, 3, 8} {
+		withMaxChunks(n, func() {
+			chunked := newModelOrFail(t, pairs...)
+			compareModels(t, n, &single, &chunked)
+		})
+	}
+}
+
+func TestChunkCount(t *testing.T) {
+	withMaxChunks(8, func() {
+		for _, test := range []struct {
+			size  int
+			slots chunkSlots
+			want  int
+		}{
+			{0, nil, 1},
+			{minChunkSize - 1, nil, 1},
+			{3 * minChunkSize, nil, 3},
+			{100 * minChunkSize, nil, 8},
+			{100 * minChunkSize, make(chunkSlots, 2), 2},
+			{minChunkSize, make(chunkSlots, 2), 1},
+		} {
+			if got := chunkCount(test.size, test.slots); got != test.want {
+				t.Errorf("chunkCount(%d, %d slots): expected %d, got %d",
+					test.size, cap(test.slots), test.want, got)
+			}
+		}
+	})
+}
+
+// slotsSource is a FilePair that records the chunk slots it was read with.
+type slotsSource struct {
+	FilePair
+	slots *chunkSlots
+}
+
+func (me slotsSource) ReadDebs(ctx context.Context,
+	add func(deb *Deb)) error {
+	*me.slots = slotsFor(ctx)
+	return me.FilePair.ReadDebs(ctx, add)
+}
+
+// TestWorkersLimitChunks checks that every source's chunks are parsed
+// within the same Workers slots however many sources are read at once.
+func TestWorkersLimitChunks(t *testing.T) {
+	dir := t.TempDir()
+	slots := make([]chunkSlots, 4)
+	var sources []Source
+	for i := range slots {
+		sources = append(sources, slotsSource{writeFixture(t, dir,
+			i*4000, 4000, "amd64", true), &slots[i]})
+	}
+	for _, workers := range []int{1, 2, 3} {
+		withMaxChunks(8, func() {
+			model, err := NewModelContext(context.Background(),
+				LoadOptions{Workers: workers}, sources...)
+			if err != nil || len(model.Debs) != 16000 {
+				t.Fatalf("workers=%d: expected 16000 packages, got %d (%v)",
+					workers, len(model.Debs), err)
+			}
+		})
+		for i, got := range slots {
+			if cap(got) != workers || got != slots[0] {
+				t.Errorf("workers=%d: source %d read with %d slots, "+
+					"expected the %d shared", workers, i, cap(got), workers)
+			}
+		}
+	}
+}
+
+func compareModels(t *testing.T, n int, single, chunked *Model) {
+	t.Helper()
+	if len(chunked.Debs) != len(single.Debs) {
+		t.Fatalf("n=%d: expected %d packages, got %d", n,
+			len(single.Debs), len(chunked.Debs))
+	}
+	for name, want := range single.Debs {
+		if deb := chunked.Debs[name]; !reflect.DeepEqual(deb, want) {
+			t.Fatalf("n=%d: %s differs:\n%+v\n%+v", n, name, want, deb)
+		}
+	}
+	for _, counts := range [][2]map[string]int{
+		{single.SectionsAndCounts, chunked.SectionsAndCounts},
+		{single.TagsAndCounts, chunked.TagsAndCounts},
+		{single.ComponentsAndCounts, chunked.ComponentsAndCounts},
+		{single.KindsAndCounts, chunked.KindsAndCounts}} {
+		if !reflect.DeepEqual(counts[0], counts[1]) {
+			t.Errorf("n=%d: counts differ: %v vs %v", n, counts[0],
+				counts[1])
+		}
+	}
+}
+
+func TestParseOrder(t *testing.T) {
+	dir := t.TempDir()
+	first := writeFixture(t, dir, 0, 4000, "amd64", false)
+	second := writeFixture(t, dir, 3000, 4000, "amd64", true)
+	withMaxChunks(8, func() {
+		model := newModelOrFail(t, first, second)
+		// Within a file the last stanza of a name wins, whichever chunk
+		// it is in; across sources the last source wins.
+		for name, version := range map[string]string{"pkg0": "9.0-0",
+			"pkg3000": "9.0-4", "pkg2999": "1.0-3",
+			"pkg3001": "1.0-5"} {
+			if deb := model.Debs[name]; deb == nil ||
+				deb.Version != version {
+				t.Errorf("expected %s v%s, got %v", name, version, deb)
+			}
+		}
+		// pkg3001's long description comes from the second source's
+		// Translation file, pkg2999's from the first's Packages file.
+		for _, name := range []string{"pkg2999", "pkg3001"} {
+			if desc := model.Debs[name].LongDesc.String(); !strings.Contains(
+				desc, "\n\nLine 2 of package") {
+				t.Errorf("%s: unexpected long description %q", name, desc)
+			}
+		}
+	})
+}
+
+func TestReadText(t *testing.T) {
+	text, err := readText(strings.NewReader(""))
+	if err != nil || text != "" {
+		t.Errorf("expected no text, got %q, %v", text, err)
+	}
+	var data bytes.Buffer
+	writeStanza(&data, nil, 1, "1.0", "amd64", false)
+	if text, err = readText(bytes.NewReader(data.Bytes())); err != nil ||
+		text != data.String() {
+		t.Fatalf("read %q (%v), expected %q", text, err, data.String())
+	}
+	var debs []*Deb
+	if err := parseDebs(context.Background(), text, "test",
+		func(deb *Deb) { debs = append(debs, deb) }); err != nil ||
+		len(debs) != 1 {
+		t.Fatalf("expected one package, got %d (%v)", len(debs), err)
+	}
+	deb := debs[0]
+	// The text shares the file data's memory so the packages' strings
+	// must be copies, else they'd keep the whole file's data alive.
+	for _, field := range []string{deb.Name, deb.Version, deb.ShortDesc,
+		deb.Depends, deb.Url, deb.Maintainer, deb.Filename, deb.Sha256,
+		deb.LongDesc.text} {
+		if field == "" || shares(field, text) {
+			t.Errorf("%q is empty or shares the file's memory", field)
+		}
+	}
+}
+
+func TestTranslationCopied(t *testing.T) {
+	var packages, translation bytes.Buffer
+	writeStanza(&packages, &translation, 1, "1.0", "amd64", true)
+	text := translation.String()
+	var longDescs []Description
+	if err := deb822.Split(text, 1)[0].Scan("test",
+		func(fields []deb822.RawField) error {
+			_, longDesc := descriptionFor(fields)
+			longDescs = append(longDescs, longDesc)
+			return nil
+		}); err != nil || len(longDescs) != 1 {
+		t.Fatalf("expected one description, got %d (%v)", len(longDescs),
+			err)
+	}
+	if longDesc := longDescs[0]; !longDesc.raw ||
+		!strings.HasPrefix(longDesc.text, " Line 0 of package 1's") ||
+		shares(longDesc.text, text) {
+		t.Errorf("unexpected or shared description %q", longDesc.text)
+	}
+}
+
+// shares returns true if s's bytes are in text's memory.
+func shares(s, text string) bool {
+	start := uintptr(unsafe.Pointer(unsafe.StringData(text)))
+	data := uintptr(unsafe.Pointer(unsafe.StringData(s)))
+	return data >= start && data < start+uintptr(len(text))
+}
+
+// BenchmarkNewModel reads real-sized Packages files: one file parsed as a
+// single chunk, the same file parsed in eight chunks (however many CPUs
+// there are), and the same packages split across several sources (as apt
+// lists are).
+func BenchmarkNewModel(b *testing.B) {
+	dir := b.TempDir()
+	whole := writeFixture(b, dir, 0, realCount, "amd64", true)
+	var parts []FilePair
+	for first := 0; first < realCount; first += realCount / 4 {
+		parts = append(parts, writeFixture(b, dir, first, realCount/4,
+			"i386", first%2 == 0))
+	}
+	for _, bench := range []struct {
+		name      string
+		maxChunks int
+		pairs     []FilePair
+	}{
+		{"single-file", 1, []FilePair{whole}},
+		{"chunked", 8, []FilePair{whole}},
+		{"multi-source", 8, parts},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			withMaxChunks(bench.maxChunks, func() {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					model := newModelOrFail(b, bench.pairs...)
+					if len(model.Debs) < realCount {
+						b.Fatalf("read %d packages", len(model.Debs))
+					}
+				}
+			})
+		})
+	}
+}
@@ -4,7 +4,6 @@
 package debsearch
 
 import (
-	"cmp"
 	"fmt"
 	"slices"
 	"strings"
@@ -13,42 +12,143 @@ import (
 )
 
 type Query struct {
-	Sections gset.Set[string] // sections are always or-ed
-	Tags     gset.Set[string]
-	TagsAnd  bool // if true all tags must match; else any
-	Words    gset.Set[string]
-	WordsAnd bool // if true all tags must match; else any
+	Components gset.Set[string] // components are always or-ed
+	Sections   gset.Set[string] // sections are always or-ed; see AddSections
+	Tags       gset.Set[string] // a tag of "facet/*" matches any facet tag
+	TagsAnd    bool             // if true all tags must match; else any
+	Kinds      gset.Set[Kind]   // kinds are always or-ed
+	NotKinds   gset.Set[Kind]   // kinds that never match
+	Words      gset.Set[string]
+	WordsAnd   bool    // if true all tags must match; else any
+	SortBy     SortKey // how to sort the matches (default by name)
+	Reverse    bool    // if true sort in descending order
+	Offset     int     // how many of the sorted matches to skip
+	Limit      int     // if > 0 the most matches to return
 }
 
 func NewQuery() *Query {
-	return &Query{Sections: gset.New[string](), Tags: gset.New[string](),
+	return &Query{Components: gset.New[string](),
+		Sections: gset.New[string](), Tags: gset.New[string](),
+		Kinds: gset.New[Kind](), NotKinds: gset.New[Kind](),
 		Words: gset.New[string]()}
 }
 
-func (me *Query) SelectFrom(model *Model) []*deb {
-	matched := gset.New[*deb]()
+// AddSections adds the sections to the query. An unqualified section,
+// e.g., "utils", matches that section in any component; a qualified one,
+// e.g., "non-free/doc", only matches that section in that component.
+func (me *Query) AddSections(sections ...string) {
+	for _, section := range sections {
+		me.Sections.Add(section)
+	}
+}
+
+// matchesSection returns true if the deb's section, or its section
+// qualified by its component, is one of the query's sections.
+func (me *Query) matchesSection(deb *Deb) bool {
+	if me.Sections.Contains(deb.Section) {
+		return true
+	}
+	component := deb.Component
+	if component == "" {
+		component = MainComponent
+	}
+	return me.Sections.Contains(component + "/" + deb.Section)
+}
+
+// Result is what a [Query] matched: the page of sorted packages (see
+// [Query.Offset] and [Query.Limit]), how many matched in all, and how many
+// of all of them are in each section, have each tag, are in each
+// component, and are of each kind, i.e., the counts to show for drilling
+// down. If the query has words, the Snippets of those Debs whose long
+// descriptions have any of them are keyed by package name.
+type Result struct {
+	Debs                []*Deb
+	Total               int
+	SectionsAndCounts   map[string]int
+	TagsAndCounts       map[string]int
+	ComponentsAndCounts map[string]int
+	KindsAndCounts      map[string]int // keyed by Kind.String()
+	Snippets            map[string][]Snippet
+}
+
+// Search returns the model's matching packages with their section, tag,
+// component, and kind counts.
+func (me *Query) Search(model *Model) Result {
+	debs := me.matches(model)
+	result := Result{Total: len(debs), SectionsAndCounts: map[string]int{},
+		TagsAndCounts: map[string]int{}, ComponentsAndCounts: map[string]int{},
+		KindsAndCounts: map[string]int{}}
+	for _, deb := range debs {
+		addCounts(deb, result.SectionsAndCounts, result.TagsAndCounts,
+			result.ComponentsAndCounts, result.KindsAndCounts)
+	}
+	result.Debs = me.page(debs)
+	result.Snippets = map[string][]Snippet{}
+	for _, deb := range result.Debs {
+		if snippets := me.Snippets(deb); len(snippets) > 0 {
+			result.Snippets[deb.Name] = snippets
+		}
+	}
+	return result
+}
+
+// SelectFrom returns the model's matching packages sorted by the query's
+// SortBy key and limited by its Offset and Limit.
+func (me *Query) SelectFrom(model *Model) []*Deb {
+	return me.page(me.matches(model))
+}
+
+func (me *Query) matches(model *Model) []*Deb {
+	debs := []*Deb{}
 	for _, deb := range model.Debs {
 		if me.Match(deb) {
-			matched.Add(deb)
+			debs = append(debs, deb)
 		}
 	}
-	slice := matched.ToSlice()
-	slices.SortFunc(slice, func(a, b *deb) int {
-		return cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
-	})
-	return slice
+	return debs
+}
+
+// page returns the sorted debs from Offset for at most Limit debs.
+func (me *Query) page(debs []*Deb) []*Deb {
+	me.sortDebs(debs)
+	debs = debs[min(max(0, me.Offset), len(debs)):]
+	if me.Limit > 0 && me.Limit < len(debs) {
+		debs = debs[:me.Limit]
+	}
+	return debs
 }
 
-func (me *Query) Match(deb *deb) bool {
-	if !me.Sections.IsEmpty() && !me.Sections.Contains(deb.Section) {
+func (me *Query) Match(deb *Deb) bool {
+	if !me.Components.IsEmpty() && !me.Components.Contains(deb.Component) {
+		return false // no specified component matches
+	}
+	if !me.Sections.IsEmpty() && !me.matchesSection(deb) {
 		return false // no specified section matches
 	}
+	if !me.Kinds.IsEmpty() || !me.NotKinds.IsEmpty() {
+		kind := deb.Kind()
+		if !me.Kinds.IsEmpty() && !me.Kinds.Contains(kind) {
+			return false // no specified kind matches
+		}
+		if me.NotKinds.Contains(kind) {
+			return false // an excluded kind
+		}
+	}
 	if !me.Tags.IsEmpty() {
-		intersection := me.Tags.Intersection(deb.Tags)
-		if intersection.IsEmpty() {
+		matches := 0
+		for tag := range me.Tags {
+			if facet, ok := IsFacetQuery(tag); ok {
+				if deb.Tags.HasFacet(facet) {
+					matches++
+				}
+			} else if deb.Tags.Contains(tag) {
+				matches++
+			}
+		}
+		if matches == 0 {
 			return false // no tags match
 		}
-		if me.TagsAnd && !me.Tags.IsSubsetOf(deb.Tags) {
+		if me.TagsAnd && matches < len(me.Tags) {
 			return false // not all tags match
 		}
 	}
@@ -66,25 +166,50 @@ func (me *Query) Match(deb *deb) bool {
 }
 
 func (me *Query) Clear() {
+	me.Components.Clear()
 	me.Sections.Clear()
 	me.Tags.Clear()
 	me.TagsAnd = false
+	me.Kinds.Clear()
+	me.NotKinds.Clear()
 	me.Words.Clear()
 	me.WordsAnd = false
+	me.SortBy = SortByName
+	me.Reverse = false
+	me.Offset = 0
+	me.Limit = 0
 }
 
 func (me *Query) String() string {
+	components := strings.Join(me.Components.ToSortedSlice(), ",")
 	sections := strings.Join(me.Sections.ToSortedSlice(), ",")
 	tags := strings.Join(me.Tags.ToSortedSlice(), ",")
 	tagOp := "|"
 	if me.TagsAnd {
 		tagOp = "&"
 	}
+	kinds := kindsString(me.Kinds)
+	notKinds := kindsString(me.NotKinds)
 	words := strings.Join(me.Words.ToSortedSlice(), " ")
 	wordOp := "|"
 	if me.WordsAnd {
 		wordOp = "&"
 	}
-	return fmt.Sprintf("sections|%q tags%s%q words%s%q", sections, tagOp,
-		tags, wordOp, words)
+	sortOrder := "+"
+	if me.Reverse {
+		sortOrder = "-"
+	}
+	return fmt.Sprintf("components|%q sections|%q tags%s%q kinds|%q "+
+		"kinds!%q words%s%q sort%s%s offset=%d limit=%d", components,
+		sections, tagOp, tags, kinds, notKinds, wordOp, words, sortOrder,
+		me.SortBy, me.Offset, me.Limit)
+}
+
+func kindsString(kinds gset.Set[Kind]) string {
+	names := make([]string, 0, len(kinds))
+	for kind := range kinds {
+		names = append(names, kind.String())
+	}
+	slices.Sort(names)
+	return strings.Join(names, ",")
 }
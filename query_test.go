@@ -0,0 +1,130 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"strings"
+	"testing"
+)
+
+// newSectionDeb returns a deb in the given section as a Packages file
+// gives it, e.g., "utils" or "non-free/doc".
+func newSectionDeb(name, section string) *Deb {
+	deb := NewDeb()
+	deb.Name = name
+	deb.Component, deb.Section = SplitSection(section)
+	return deb
+}
+
+func TestQuerySections(t *testing.T) {
+	debs := []*Deb{newSectionDeb("foo", "utils"),
+		newSectionDeb("foo-doc", "non-free/doc"),
+		newSectionDeb("bar-doc", "doc"),
+		newSectionDeb("baz", "contrib/utils"),
+		newSectionDeb("qux", "non-free/libs")}
+	for _, test := range []struct {
+		sections   []string
+		components []string
+		want       string
+	}{
+		{[]string{"non-free/doc", "utils"}, nil, "foo foo-doc baz"},
+		{[]string{"main/utils"}, nil, "foo"},
+		{[]string{"doc"}, nil, "foo-doc bar-doc"},
+		{[]string{"non-free/doc", "utils"}, []string{"main"}, "foo"},
+		{[]string{"non-free/doc", "libs"}, []string{"non-free"},
+			"foo-doc qux"},
+	} {
+		query := NewQuery()
+		query.AddSections(test.sections...)
+		query.Components.Add(test.components...)
+		got := ""
+		for _, deb := range debs {
+			if query.Match(deb) {
+				if got != "" {
+					got += " "
+				}
+				got += deb.Name
+			}
+		}
+		if got != test.want {
+			t.Errorf("sections %v components %v: expected %q, got %q",
+				test.sections, test.components, test.want, got)
+		}
+	}
+}
+
+func debNames(debs []*Deb) string {
+	names := make([]string, 0, len(debs))
+	for _, deb := range debs {
+		names = append(names, deb.Name)
+	}
+	return strings.Join(names, " ")
+}
+
+func TestSortAndPage(t *testing.T) {
+	model := modelOf(t, `Package: zed
+Version: 1.10-1
+Installed-Size: 300
+Section: editors
+Description: an editor
+
+Package: Alpha
+Version: 1.9-1
+Installed-Size: 100
+Section: utils
+Description: an editor for editors
+
+Package: beta
+Version: 1:0.1-1
+Installed-Size: 200
+Section: contrib/editors
+Description: a tool
+ An editor.
+
+Package: gamma
+Version: 1.10~rc1-1
+Installed-Size: 100
+Section: editors
+Description: a tool`)
+	for _, test := range []struct {
+		sortBy  SortKey
+		reverse bool
+		offset  int
+		limit   int
+		want    string
+		total   int
+	}{
+		{SortByName, false, 0, 0, "Alpha beta gamma zed", 4},
+		{SortByName, true, 0, 0, "zed gamma beta Alpha", 4},
+		{SortBySize, false, 0, 0, "Alpha gamma beta zed", 4}, // ties by name
+		{SortBySize, true, 0, 0, "zed beta gamma Alpha", 4},
+		{SortBySection, false, 0, 0, "beta gamma zed Alpha", 4},
+		{SortByVersion, false, 0, 0, "Alpha gamma zed beta", 4},
+		{SortByRelevance, false, 0, 0, "zed Alpha beta", 3},
+		{SortByName, false, 1, 2, "beta gamma", 4},
+		{SortByName, true, 3, 2, "Alpha", 4},
+		{SortByName, false, 9, 0, "", 4},
+		{SortByName, false, -1, -1, "Alpha beta gamma zed", 4},
+	} {
+		query := NewQuery()
+		query.Sections.Add("editors", "utils")
+		query.SortBy, query.Reverse = test.sortBy, test.reverse
+		query.Offset, query.Limit = test.offset, test.limit
+		if test.sortBy == SortByRelevance {
+			query.Sections.Clear()
+			query.Words.Add("editor", "zed")
+		}
+		result := query.Search(&model)
+		if got := debNames(result.Debs); got != test.want ||
+			result.Total != test.total {
+			t.Errorf("%s reverse=%t offset=%d limit=%d: expected %q of "+
+				"%d, got %q of %d", test.sortBy, test.reverse, test.offset,
+				test.limit, test.want, test.total, got, result.Total)
+		}
+		if got := debNames(query.SelectFrom(&model)); got != test.want {
+			t.Errorf("%s: SelectFrom expected %q, got %q", test.sortBy,
+				test.want, got)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type RelationKind uint8
+
+const (
+	PreDependsKind RelationKind = iota
+	DependsKind
+	RecommendsKind
+	SuggestsKind
+)
+
+func (me RelationKind) String() string {
+	switch me {
+	case PreDependsKind:
+		return "Pre-Depends"
+	case DependsKind:
+		return "Depends"
+	case RecommendsKind:
+		return "Recommends"
+	case SuggestsKind:
+		return "Suggests"
+	}
+	return "BUG: invalid RelationKind"
+}
+
+// Relation is one alternative in a Depends-style field, e.g.,
+// "libc6 (>= 2.34)" is Name "libc6", Op ">=", Version "2.34".
+type Relation struct {
+	Name    string
+	Arch    string // e.g., "any" for "python3:any"
+	Op      string
+	Version string
+}
+
+func (me Relation) String() string {
+	name := me.Name
+	if me.Arch != "" {
+		name += ":" + me.Arch
+	}
+	if me.Op == "" {
+		return name
+	}
+	return fmt.Sprintf("%s (%s %s)", name, me.Op, me.Version)
+}
+
+// SatisfiedBy returns true if a package of the given version satisfies the
+// relation's version constraint (if any).
+func (me Relation) SatisfiedBy(version string) bool {
+	if me.Op == "" {
+		return true
+	}
+	result := CompareVersions(version, me.Version)
+	switch me.Op {
+	case "<<":
+		return result < 0
+	case "<=", "<": // "<" and ">" are obsolete forms of "<=" and ">="
+		return result <= 0
+	case "=":
+		return result == 0
+	case ">=", ">":
+		return result >= 0
+	case ">>":
+		return result > 0
+	}
+	return false
+}
+
+// ParseRelations parses a Depends-style field into its and-ed groups of
+// or-ed alternatives, e.g., "a, b | c" → [[a] [b c]]. Architecture
+// restrictions ([...]) and build profiles (<...>) are dropped.
+func ParseRelations(field string) [][]Relation {
+	groups := [][]Relation{}
+	for _, group := range strings.Split(field, ",") {
+		alternatives := []Relation{}
+		for _, text := range strings.Split(group, "|") {
+			if relation, ok := parseRelation(text); ok {
+				alternatives = append(alternatives, relation)
+			}
+		}
+		if len(alternatives) > 0 {
+			groups = append(groups, alternatives)
+		}
+	}
+	return groups
+}
+
+func parseRelation(text string) (Relation, bool) {
+	text = stripRestrictions(text)
+	relation := Relation{}
+	text = strings.TrimSpace(text)
+	if i := strings.IndexByte(text, '('); i > -1 {
+		constraint := strings.Trim(text[i:], "() ")
+		text = strings.TrimSpace(text[:i])
+		j := strings.IndexAny(constraint, "0123456789")
+		if j == -1 {
+			return relation, false
+		}
+		relation.Op = strings.TrimSpace(constraint[:j])
+		relation.Version = strings.TrimSpace(constraint[j:])
+	}
+	relation.Name, relation.Arch, _ = strings.Cut(text, ":")
+	return relation, relation.Name != ""
+}
+
+// stripRestrictions drops any architecture restrictions ([...]) and
+// build profiles (<...>) but not version constraints such as (<< 2).
+func stripRestrictions(text string) string {
+	var builder strings.Builder
+	inParens := false
+	var skipTo byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case skipTo != 0:
+			if c == skipTo {
+				skipTo = 0
+			}
+		case !inParens && c == '[':
+			skipTo = ']'
+		case !inParens && c == '<':
+			skipTo = '>'
+		default:
+			if c == '(' {
+				inParens = true
+			} else if c == ')' {
+				inParens = false
+			}
+			builder.WriteByte(c)
+		}
+	}
+	return builder.String()
+}
+
+// Relations returns the deb's parsed relations of the given kind.
+func (me *Deb) Relations(kind RelationKind) [][]Relation {
+	switch kind {
+	case PreDependsKind:
+		return ParseRelations(me.PreDepends)
+	case DependsKind:
+		return ParseRelations(me.Depends)
+	case RecommendsKind:
+		return ParseRelations(me.Recommends)
+	case SuggestsKind:
+		return ParseRelations(me.Suggests)
+	}
+	return nil
+}
+
+// ProvidedNames returns the names of the virtual packages the deb
+// provides (without versions).
+func (me *Deb) ProvidedNames() []string {
+	names := []string{}
+	for _, group := range ParseRelations(me.Provides) {
+		for _, relation := range group {
+			names = append(names, relation.Name)
+		}
+	}
+	return names
+}
+
+// ReverseRelation is a package that refers to another package via one of
+// its relation fields.
+type ReverseRelation struct {
+	Deb  *Deb
+	Kind RelationKind
+}
+
+// ReverseRelations returns every package that has a relation of one of
+// the given kinds to the named package or to any virtual package it
+// provides, sorted by name.
+func (me *Model) ReverseRelations(name string,
+	kinds ...RelationKind) []ReverseRelation {
+	names := map[string]bool{name: true}
+	if deb, ok := me.Debs[name]; ok {
+		for _, provided := range deb.ProvidedNames() {
+			names[provided] = true
+		}
+	}
+	reverse := []ReverseRelation{}
+	for _, deb := range me.Debs {
+		for _, kind := range kinds {
+			if debRefersTo(deb, kind, names) {
+				reverse = append(reverse, ReverseRelation{deb, kind})
+			}
+		}
+	}
+	slices.SortFunc(reverse, func(a, b ReverseRelation) int {
+		if c := cmp.Compare(a.Deb.Name, b.Deb.Name); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Kind, b.Kind)
+	})
+	return reverse
+}
+
+func debRefersTo(deb *Deb, kind RelationKind, names map[string]bool) bool {
+	for _, group := range deb.Relations(kind) {
+		for _, relation := range group {
+			if names[relation.Name] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Providers returns the packages that provide the given virtual package,
+// sorted by name.
+func (me *Model) Providers(name string) []*Deb {
+	providers := []*Deb{}
+	for _, deb := range me.Debs {
+		if slices.Contains(deb.ProvidedNames(), name) {
+			providers = append(providers, deb)
+		}
+	}
+	slices.SortFunc(providers, func(a, b *Deb) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return providers
+}
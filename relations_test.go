@@ -0,0 +1,62 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRelations(t *testing.T) {
+	for _, test := range []struct {
+		field string
+		want  [][]Relation
+	}{
+		{"", [][]Relation{}},
+		{"libc6", [][]Relation{{{Name: "libc6"}}}},
+		{"libc6 (>= 2.34), zlib1g | libz-ng", [][]Relation{
+			{{Name: "libc6", Op: ">=", Version: "2.34"}},
+			{{Name: "zlib1g"}, {Name: "libz-ng"}}}},
+		{"python3:any (>= 3.11~), libfoo1:i386 (<< 2:1.0)", [][]Relation{
+			{{Name: "python3", Arch: "any", Op: ">=", Version: "3.11~"}},
+			{{Name: "libfoo1", Arch: "i386", Op: "<<",
+				Version: "2:1.0"}}}},
+		{"perl:native", [][]Relation{{{Name: "perl", Arch: "native"}}}},
+		{"libfoo-dev [amd64 i386] <!nocheck>, bar [!hurd-any] (= 1.0)",
+			[][]Relation{{{Name: "libfoo-dev"}},
+				{{Name: "bar", Op: "=", Version: "1.0"}}}},
+		{"foo (>>1.0)", [][]Relation{{{Name: "foo", Op: ">>",
+			Version: "1.0"}}}},
+		{"foo (>= ), , bar", [][]Relation{{{Name: "bar"}}}},
+	} {
+		if got := ParseRelations(test.field); !reflect.DeepEqual(got,
+			test.want) {
+			t.Errorf("%q: expected %v, got %v", test.field, test.want, got)
+		}
+	}
+}
+
+func TestSatisfiedBy(t *testing.T) {
+	for _, test := range []struct {
+		relation string
+		version  string
+		want     bool
+	}{
+		{"foo", "0.1", true},
+		{"foo (>= 1.0)", "1.0", true},
+		{"foo (>= 1.0)", "1.0~rc1", false},
+		{"foo (>> 1.0)", "1.0", false},
+		{"foo (<< 1.0)", "1.0~rc1", true},
+		{"foo (<= 1.0)", "1.0", true},
+		{"foo (= 1:1.0)", "1.0", false},
+		{"foo (< 1.0)", "1.0", true}, // obsolete form of <=
+		{"foo (> 1.0)", "1.0", true}, // obsolete form of >=
+	} {
+		relation := ParseRelations(test.relation)[0][0]
+		if got := relation.SatisfiedBy(test.version); got != test.want {
+			t.Errorf("%s satisfied by %s: expected %t, got %t",
+				relation, test.version, test.want, got)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import "strings"
+
+const (
+	maxSnippets    = 2  // per package
+	snippetContext = 32 // bytes either side of a matched word (at most)
+)
+
+// Snippet is a keyword-in-context excerpt of a long description: its Text
+// has whitespace runs collapsed and "…" where it was cut, and each of its
+// Highlights is the byte [start, end) of a matched word in Text.
+type Snippet struct {
+	Text       string
+	Highlights [][2]int
+}
+
+// Snippets returns excerpts of the deb's long description around the
+// query's words (nil if the query has no words or none are in the long
+// description).
+func (me *Query) Snippets(deb *Deb) []Snippet {
+	if me.Words.IsEmpty() {
+		return nil
+	}
+	text := deb.LongDesc.String()
+	matches := me.wordMatches(LongDescField, text)
+	var snippets []Snippet
+	for len(matches) > 0 && len(snippets) < maxSnippets {
+		start := max(0, matches[0].Start-snippetContext)
+		end := min(len(text), matches[0].End+snippetContext)
+		count := 1 // matches whose windows overlap this snippet's
+		for ; count < len(matches); count++ {
+			match := matches[count]
+			if match.Start-snippetContext >= end {
+				break
+			}
+			end = min(len(text), max(end, match.End+snippetContext))
+		}
+		snippets = append(snippets, makeSnippet(text,
+			wordStart(text, start, matches[0].Start),
+			wordEnd(text, end, matches[count-1].End), matches[:count]))
+		matches = matches[count:]
+	}
+	return snippets
+}
+
+// wordStart returns start moved forward to the start of a word (so that
+// a snippet doesn't begin mid-word) but not beyond limit.
+func wordStart(text string, start, limit int) int {
+	if start == 0 || strings.IndexByte(asciiWs, text[start-1]) > -1 {
+		return start
+	}
+	if i := strings.IndexAny(text[start:limit], asciiWs); i > -1 {
+		return start + i + 1
+	}
+	return limit
+}
+
+// wordEnd returns end moved back to the end of a word but not before
+// limit.
+func wordEnd(text string, end, limit int) int {
+	if end == len(text) || strings.IndexByte(asciiWs, text[end]) > -1 {
+		return end
+	}
+	if i := strings.LastIndexAny(text[limit:end], asciiWs); i > -1 {
+		return limit + i
+	}
+	return limit
+}
+
+func makeSnippet(text string, start, end int,
+	matches []WordMatch) Snippet {
+	var snippet strings.Builder
+	highlights := make([][2]int, 0, len(matches))
+	if start > 0 {
+		snippet.WriteString("…")
+	}
+	inSpace := false
+	for i := start; i < end; i++ {
+		for _, match := range matches {
+			if match.Start == i {
+				highlights = append(highlights, [2]int{snippet.Len(),
+					snippet.Len() + match.End - match.Start})
+			}
+		}
+		if c := text[i]; strings.IndexByte(asciiWs, c) > -1 {
+			if !inSpace {
+				snippet.WriteByte(' ')
+			}
+			inSpace = true
+		} else {
+			snippet.WriteByte(c)
+			inSpace = false
+		}
+	}
+	if end < len(text) {
+		snippet.WriteString("…")
+	}
+	return Snippet{Text: snippet.String(), Highlights: highlights}
+}
+
+// Highlighted returns the snippet's text with each highlight wrapped in
+// before and after, e.g., ANSI codes or HTML tags; if escape isn't nil it
+// is applied to the text between them, e.g., html.EscapeString.
+func (me Snippet) Highlighted(before, after string,
+	escape func(string) string) string {
+	if escape == nil {
+		escape = func(s string) string { return s }
+	}
+	var text strings.Builder
+	pos := 0
+	for _, highlight := range me.Highlights {
+		text.WriteString(escape(me.Text[pos:highlight[0]]))
+		text.WriteString(before)
+		text.WriteString(escape(me.Text[highlight[0]:highlight[1]]))
+		text.WriteString(after)
+		pos = highlight[1]
+	}
+	text.WriteString(escape(me.Text[pos:]))
+	return text.String()
+}
@@ -0,0 +1,49 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSnippets(t *testing.T) {
+	model := modelOf(t, `Package: pkg
+Description: a package
+ The first paragraph mentions nothing of interest at all, it just
+ goes on for a while to pad the text out a little.
+ .
+ Then   the   editor   appears here and after it the EDITOR again, then
+ lots more text so that the next match is a separate snippet entirely.
+ Finally the editor is mentioned once more at the end.`)
+	deb := model.Debs["pkg"]
+	query := NewQuery()
+	if snippets := query.Snippets(deb); snippets != nil {
+		t.Errorf("expected no snippets without words, got %v", snippets)
+	}
+	query.Words.Add("editor", "absent")
+	snippets := query.Snippets(deb)
+	// Whitespace runs (including paragraph breaks) are collapsed, cuts
+	// are at word boundaries, and highlights are byte offsets ("…" is 3).
+	want := []Snippet{
+		{"…out a little. Then the editor appears here and after it the " +
+			"EDITOR again, then lots more text so…",
+			[][2]int{{26, 32}, {63, 69}}},
+		{"…snippet entirely. Finally the editor is mentioned once more " +
+			"at the…", [][2]int{{33, 39}}}}
+	if !reflect.DeepEqual(snippets, want) {
+		t.Fatalf("expected\n%q\ngot\n%q", want, snippets)
+	}
+	if got := snippets[1].Highlighted("<b>", "</b>",
+		strings.ToUpper); got != "…SNIPPET ENTIRELY. FINALLY THE "+
+		"<b>EDITOR</b> IS MENTIONED ONCE MORE AT THE…" {
+		t.Errorf("unexpected highlighting %q", got)
+	}
+	query.Words.Clear()
+	query.Words.Add("absent")
+	if snippets := query.Snippets(deb); snippets != nil {
+		t.Errorf("expected no snippets for absent words, got %v", snippets)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SortKey is what a [Query]'s results are sorted by; ties are broken by
+// name.
+type SortKey uint8
+
+const (
+	SortByName         SortKey = iota // case-insensitively
+	SortBySize                        // installed size, smallest first
+	SortByDownloadSize                // smallest first
+	SortBySection                     // by component then section
+	SortByVersion                     // oldest first
+	SortByRelevance                   // most relevant to the words first
+)
+
+var sortKeyNames = [...]string{"name", "size", "download-size", "section",
+	"version", "relevance"}
+
+// SortKeyNames returns the names that [ParseSortKey] accepts.
+func SortKeyNames() []string { return slices.Clone(sortKeyNames[:]) }
+
+// ParseSortKey returns the SortKey of the given name, e.g., "size".
+func ParseSortKey(name string) (SortKey, error) {
+	if i := slices.Index(sortKeyNames[:], name); i > -1 {
+		return SortKey(i), nil
+	}
+	return SortByName, fmt.Errorf("%w: %q", Err110, name)
+}
+
+func (me SortKey) String() string {
+	if int(me) < len(sortKeyNames) {
+		return sortKeyNames[me]
+	}
+	return fmt.Sprintf("SortKey(%d)", me)
+}
+
+// sortDebs sorts the debs by the query's SortBy key (in reverse if the
+// query's Reverse is true).
+func (me *Query) sortDebs(debs []*Deb) {
+	var scores map[*Deb]int
+	if me.SortBy == SortByRelevance {
+		scores = make(map[*Deb]int, len(debs))
+		for _, deb := range debs {
+			scores[deb] = me.Relevance(deb)
+		}
+	}
+	slices.SortFunc(debs, func(a, b *Deb) int {
+		result := 0
+		switch me.SortBy {
+		case SortBySize:
+			result = cmp.Compare(a.Size, b.Size)
+		case SortByDownloadSize:
+			result = cmp.Compare(a.DownloadSize, b.DownloadSize)
+		case SortBySection:
+			if result = cmp.Compare(a.Component, b.Component); result == 0 {
+				result = cmp.Compare(a.Section, b.Section)
+			}
+		case SortByVersion:
+			result = CompareVersions(a.Version, b.Version)
+		case SortByRelevance:
+			result = cmp.Compare(scores[b], scores[a])
+		}
+		if result == 0 {
+			result = compareNames(a, b)
+		}
+		if me.Reverse {
+			return -result
+		}
+		return result
+	})
+}
+
+func compareNames(a, b *Deb) int {
+	if result := cmp.Compare(strings.ToLower(a.Name),
+		strings.ToLower(b.Name)); result != 0 {
+		return result
+	}
+	return cmp.Compare(a.Name, b.Name)
+}
+
+// Relevance returns how well the deb matches the query's words: each word
+// scores most for being the name, then for being in the name, then in the
+// short description, and least for being in the long description.
+func (me *Query) Relevance(deb *Deb) int {
+	if me.Words.IsEmpty() {
+		return 0
+	}
+	name := strings.ToLower(deb.Name)
+	nameWords := textWords(deb.Name)
+	shortWords := textWords(deb.ShortDesc)
+	var longWords map[string]bool // only computed if needed
+	score := 0
+	for word := range me.Words {
+		switch {
+		case word == name:
+			score += 8
+		case nameWords[word]:
+			score += 4
+		case shortWords[word]:
+			score += 2
+		default:
+			if longWords == nil {
+				longWords = textWords(deb.LongDesc.String())
+			}
+			if longWords[word] {
+				score++
+			}
+		}
+	}
+	return score
+}
+
+// textWords returns the text's words case-folded, split as [Deb.Words]
+// splits them.
+func textWords(text string) map[string]bool {
+	words := map[string]bool{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(text),
+		isNonWordRune) {
+		words[word] = true
+	}
+	return words
+}
+
+// isNonWordRune is true for the runes that regexp's \W matches.
+func isNonWordRune(r rune) bool {
+	return !(r == '_' || ('0' <= r && r <= '9') || ('a' <= r && r <= 'z') ||
+		('A' <= r && r <= 'Z'))
+}
@@ -0,0 +1,137 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mark-summerfield/debsearch/deb822"
+)
+
+// Source is anything that can provide packages to a [Model]; see
+// [NewModelFromSources]. The library's sources are [FilePair], [AptLists],
+// [Mirror], [DpkgStatus], [DebDir], and [DebList].
+type Source interface {
+	// Origin describes where the source's packages come from.
+	Origin() Origin
+	// ReadDebs calls add for every valid package the source provides.
+	// It should stop and return ctx.Err() if ctx is cancelled; wrapping
+	// any files read with [NewContextReader] does this (and reports the
+	// bytes read as progress).
+	ReadDebs(ctx context.Context, add func(deb *Deb)) error
+	// ReadDescriptions calls add for every separately held long
+	// description (e.g., from a Translation file); sources whose
+	// packages already have their long descriptions may do nothing.
+	ReadDescriptions(ctx context.Context,
+		add func(name string, longDesc Description)) error
+}
+
+type Origin struct {
+	Kind     string // e.g., "apt-lists", "mirror", "dpkg-status"
+	Location string // e.g., a filename, directory, or label
+}
+
+func (me Origin) String() string {
+	if me.Location == "" {
+		return me.Kind
+	}
+	return fmt.Sprintf("%s %s", me.Kind, me.Location)
+}
+
+// DebList is an in-memory Source, e.g., for library users who build or
+// fetch their own packages.
+type DebList []*Deb
+
+func (me DebList) Origin() Origin { return Origin{Kind: "memory"} }
+
+func (me DebList) ReadDebs(ctx context.Context, add func(deb *Deb)) error {
+	for _, deb := range me {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if deb.IsValid() {
+			add(deb)
+		}
+	}
+	return nil
+}
+
+func (me DebList) ReadDescriptions(ctx context.Context,
+	add func(name string, longDesc Description)) error {
+	return nil
+}
+
+// AptLists is the Source for the apt lists in Dir (normally [ListsPath])
+// for the given Arc.
+type AptLists struct {
+	Dir              string
+	Arc              string
+	WithDescriptions bool
+}
+
+func NewAptLists(dir, arc string, withDescriptions bool) AptLists {
+	return AptLists{Dir: dir, Arc: arc, WithDescriptions: withDescriptions}
+}
+
+func (me AptLists) FilePairs() []FilePair {
+	return stdFilePairs(me.Dir, me.Arc, me.WithDescriptions)
+}
+
+func (me AptLists) Origin() Origin {
+	return Origin{Kind: "apt-lists", Location: me.Dir}
+}
+
+func (me AptLists) ReadDebs(ctx context.Context, add func(deb *Deb)) error {
+	return readAll(ctx, me.FilePairs(), func(pair FilePair) error {
+		return pair.ReadDebs(ctx, add)
+	})
+}
+
+func (me AptLists) ReadDescriptions(ctx context.Context,
+	add func(name string, longDesc Description)) error {
+	return readAll(ctx, me.FilePairs(), func(pair FilePair) error {
+		return pair.ReadDescriptions(ctx, add)
+	})
+}
+
+// readAll reads every pair (unless ctx is cancelled) and returns all
+// their errors joined.
+func readAll(ctx context.Context, pairs []FilePair,
+	read func(pair FilePair) error) error {
+	if len(pairs) == 0 {
+		return Err102
+	}
+	var errs []error
+	for _, pair := range pairs {
+		if err := read(pair); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// readStanzaDebs calls add for every valid package in the given Packages
+// data; see [parseDebs].
+func readStanzaDebs(ctx context.Context, file io.Reader, label string,
+	add func(deb *Deb)) error {
+	text, err := readText(file)
+	if err != nil {
+		return err
+	}
+	return parseDebs(ctx, text, label, add)
+}
+
+func debForStanza(stanza *deb822.Stanza) *Deb {
+	deb := NewDeb()
+	for _, field := range stanza.Fields {
+		addField(deb, field.Name, field.Value)
+	}
+	return deb
+}
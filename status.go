@@ -0,0 +1,94 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mark-summerfield/debsearch/deb822"
+)
+
+// StatusPathForRoot returns the dpkg status file for a chroot or an
+// image's root filesystem, e.g., root/var/lib/dpkg/status.
+func StatusPathForRoot(root string) string {
+	return filepath.Join(root, StatusPath)
+}
+
+// InstalledVersions returns the version of every installed package as
+// recorded in the given dpkg status file (normally [StatusPath]) keyed by
+// [InstalledKey], so that a multiarch package's versions are kept apart,
+// e.g., "libc6:amd64" and "libc6:i386".
+func InstalledVersions(statusFile string) (map[string]string, error) {
+	installed := map[string]string{}
+	file, err := os.Open(statusFile)
+	if err != nil {
+		return installed, fmt.Errorf("%w: %s", Err105, err)
+	}
+	defer file.Close()
+	reader := deb822.NewReaderLabel(file, statusFile)
+	for {
+		stanza, err := reader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return installed, fmt.Errorf("%w: %s", Err105, err)
+		}
+		if strings.HasSuffix(stanza.Get("Status"), " installed") {
+			installed[InstalledKey(stanza.Get("Package"),
+				stanza.Get("Architecture"))] = stanza.Get("Version")
+		}
+	}
+	return installed, nil
+}
+
+// InstalledKey returns the [InstalledVersions] key for the named package
+// of the given arch, e.g., "libc6:amd64" or "tzdata:all".
+func InstalledKey(name, arch string) string { return name + ":" + arch }
+
+// DpkgStatus is the Source for the installed packages recorded in a dpkg
+// status file (normally [StatusPath]).
+type DpkgStatus struct {
+	Filename string
+}
+
+func NewDpkgStatus(filename string) DpkgStatus {
+	return DpkgStatus{Filename: filename}
+}
+
+func (me DpkgStatus) Origin() Origin {
+	return Origin{Kind: "dpkg-status", Location: me.Filename}
+}
+
+func (me DpkgStatus) ReadDebs(ctx context.Context, add func(deb *Deb)) error {
+	file, err := os.Open(me.Filename)
+	if err != nil {
+		return fmt.Errorf("%w: %s", Err105, err)
+	}
+	defer file.Close()
+	reader := deb822.NewReaderLabel(NewContextReader(ctx, file),
+		me.Filename)
+	for {
+		stanza, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("%w: %s", Err105, err)
+		}
+		if strings.HasSuffix(stanza.Get("Status"), " installed") {
+			if deb := debForStanza(&stanza); deb.IsValid() {
+				add(deb)
+			}
+		}
+	}
+}
+
+func (me DpkgStatus) ReadDescriptions(ctx context.Context,
+	add func(name string, longDesc Description)) error {
+	return nil // dpkg status stanzas have their long descriptions
+}
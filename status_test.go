@@ -0,0 +1,43 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestInstalledVersions(t *testing.T) {
+	statusFile := filepath.Join(t.TempDir(), "status")
+	writeFile(t, statusFile, []byte(`Package: libc6
+Status: install ok installed
+Architecture: amd64
+Version: 2.36-9
+
+Package: libc6
+Status: install ok installed
+Architecture: i386
+Version: 2.36-8
+
+Package: tzdata
+Status: install ok installed
+Architecture: all
+Version: 2024a-0
+
+Package: gone
+Status: deinstall ok config-files
+Architecture: amd64
+Version: 1
+`))
+	installed, err := InstalledVersions(statusFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"libc6:amd64": "2.36-9",
+		"libc6:i386": "2.36-8", "tzdata:all": "2024a-0"}
+	if !reflect.DeepEqual(installed, expected) {
+		t.Errorf("expected %v, got %v", expected, installed)
+	}
+}
@@ -10,9 +10,9 @@ import (
 	"github.com/mark-summerfield/gong"
 )
 
-func stdFilePairs(arc string, withDescriptions bool) []FilePair {
+func stdFilePairs(listsDir, arc string, withDescriptions bool) []FilePair {
 	pairs := []FilePair{}
-	glob := filepath.Join(listsPath, "*"+arc+"_Packages")
+	glob := filepath.Join(listsDir, "*"+arc+"_Packages")
 	if matches, err := filepath.Glob(glob); err == nil {
 		for _, pkgFile := range matches {
 			descFile := ""
@@ -25,9 +25,14 @@ func stdFilePairs(arc string, withDescriptions bool) []FilePair {
 	return pairs
 }
 
+// descFileForPackageFile returns the Packages file's Translation-en file
+// or "" if there isn't one (e.g., apt hasn't fetched it).
 func descFileForPackageFile(filename string) string {
 	if prefix, _, found := strings.Cut(filename, "_binary"); found {
-		return prefix + "_i18n_Translation-en"
+		if descFile := prefix + "_i18n_Translation-en"; gong.FileExists(
+			descFile) {
+			return descFile
+		}
 	}
 	return ""
 }
@@ -45,3 +50,19 @@ func HumanSize(size int) string {
 	}
 	return gong.Commas(size) + units
 }
+
+// HumanBytes is like [HumanSize] but for sizes given in bytes (e.g., a
+// deb's DownloadSize) rather than in KB (e.g., a deb's Size).
+func HumanBytes(size int) string {
+	return HumanSize((size + 1023) / 1024)
+}
+
+// trimRightWs is strings.TrimRight(s, " \t\r") without the overhead.
+func trimRightWs(s string) string {
+	end := len(s)
+	for end > 0 && (s[end-1] == ' ' || s[end-1] == '\t' ||
+		s[end-1] == '\r') {
+		end--
+	}
+	return s[:end]
+}
@@ -0,0 +1,98 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"strconv"
+	"strings"
+)
+
+// CompareVersions compares two Debian package versions (of the form
+// [epoch:]upstream[-revision]) the way dpkg does, returning -1 if a is
+// older than b, 0 if they are equal, and 1 if a is newer than b.
+func CompareVersions(a, b string) int {
+	aEpoch, aUpstream, aRevision := splitVersion(a)
+	bEpoch, bUpstream, bRevision := splitVersion(b)
+	if aEpoch != bEpoch {
+		return sign(aEpoch - bEpoch)
+	}
+	if result := compareVersionParts(aUpstream, bUpstream); result != 0 {
+		return sign(result)
+	}
+	return sign(compareVersionParts(aRevision, bRevision))
+}
+
+func splitVersion(version string) (int, string, string) {
+	epoch := 0
+	if before, after, found := strings.Cut(version, ":"); found {
+		epoch, _ = strconv.Atoi(before)
+		version = after
+	}
+	revision := ""
+	if i := strings.LastIndexByte(version, '-'); i > -1 {
+		version, revision = version[:i], version[i+1:]
+	}
+	return epoch, version, revision
+}
+
+// compareVersionParts is dpkg's verrevcmp: alternate runs of non-digits
+// (compared by versionOrder) and digits (compared numerically).
+func compareVersionParts(a, b string) int {
+	for a != "" || b != "" {
+		for (a != "" && !isDigit(a[0])) || (b != "" && !isDigit(b[0])) {
+			aOrder, bOrder := versionOrder(a), versionOrder(b)
+			if aOrder != bOrder {
+				return aOrder - bOrder
+			}
+			a, b = a[min(1, len(a)):], b[min(1, len(b)):]
+		}
+		a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+		firstDiff := 0
+		for a != "" && isDigit(a[0]) && b != "" && isDigit(b[0]) {
+			if firstDiff == 0 {
+				firstDiff = int(a[0]) - int(b[0])
+			}
+			a, b = a[1:], b[1:]
+		}
+		if a != "" && isDigit(a[0]) {
+			return 1
+		}
+		if b != "" && isDigit(b[0]) {
+			return -1
+		}
+		if firstDiff != 0 {
+			return firstDiff
+		}
+	}
+	return 0
+}
+
+// versionOrder returns the sort weight of s's first character: "~" sorts
+// before anything (even the end of the string), then digits and the end,
+// then letters, then everything else.
+func versionOrder(s string) int {
+	switch {
+	case s == "" || isDigit(s[0]):
+		return 0
+	case s[0] == '~':
+		return -1
+	case ('a' <= s[0] && s[0] <= 'z') || ('A' <= s[0] && s[0] <= 'Z'):
+		return int(s[0])
+	default:
+		return int(s[0]) + 256
+	}
+}
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
+
+func sign(i int) int {
+	switch {
+	case i < 0:
+		return -1
+	case i > 0:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	for _, test := range []struct {
+		a, b string
+		want int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0-1", "1.0-2", -1},
+		{"1.10", "1.9", 1},
+		{"1.0", "1.0-0", 0},     // no revision is revision 0
+		{"0:1.0", "1.0", 0},     // no epoch is epoch 0
+		{"1:0.1", "9.9", 1},     // the epoch comes first
+		{"2:1.0", "10:1.0", -1}, // epochs compare as numbers
+		{"1.0~rc1", "1.0", -1},  // ~ sorts before the empty string
+		{"1.0~rc1", "1.0~rc2", -1},
+		{"1.0~~", "1.0~", -1},
+		{"1.0~", "1.0", -1},
+		{"1.0+b1", "1.0", 1}, // + sorts after the empty string
+		{"1.0a", "1.0+", -1}, // letters sort before non-letters
+		{"1.0.0", "1.0", 1},
+		{"2.36-9+deb12u4", "2.36-9", 1},
+		{"2.36-9+deb12u4", "2.36-10", -1},
+		{"1.2.3-1~bpo12+1", "1.2.3-1", -1}, // backports sort lower
+		{"007", "7", 0},                    // leading zeros don't count
+	} {
+		if got := CompareVersions(test.a, test.b); got != test.want {
+			t.Errorf("CompareVersions(%q, %q): expected %d, got %d",
+				test.a, test.b, test.want, got)
+		}
+		if got := CompareVersions(test.b, test.a); got != -test.want {
+			t.Errorf("CompareVersions(%q, %q): expected %d, got %d",
+				test.b, test.a, -test.want, got)
+		}
+	}
+}
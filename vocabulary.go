@@ -0,0 +1,172 @@
+// Copyright © 2023 Mark Summerfield. All rights reserved.
+// License: GPL-3
+
+package debsearch
+
+import (
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/mark-summerfield/debsearch/deb822"
+)
+
+//go:embed vocabulary.dat
+var bundledVocabulary string
+
+// Facet is a debtags facet, e.g., "use", with its tags' full names, e.g.,
+// "use/viewing", in order.
+type Facet struct {
+	Name      string
+	ShortDesc string
+	LongDesc  string
+	Tags      []string
+}
+
+// TagInfo describes a tag; its Name is the full name, e.g., "use/viewing".
+type TagInfo struct {
+	Name      string
+	Facet     string
+	ShortDesc string
+	LongDesc  string
+}
+
+// Vocabulary holds the debtags facet and tag descriptions keyed by facet
+// name and by full tag name respectively.
+type Vocabulary struct {
+	Facets map[string]*Facet
+	Tags   map[string]*TagInfo
+}
+
+func newVocabulary() *Vocabulary {
+	return &Vocabulary{Facets: map[string]*Facet{},
+		Tags: map[string]*TagInfo{}}
+}
+
+// DefaultVocabulary returns the system's debtags vocabulary (from
+// [VocabularyPath]) if it can be read, or else the bundled copy.
+func DefaultVocabulary() *Vocabulary {
+	if vocabulary, err := ReadVocabulary(VocabularyPath); err == nil {
+		return vocabulary
+	}
+	return BundledVocabulary()
+}
+
+// BundledVocabulary returns the abridged vocabulary built into debsearch.
+func BundledVocabulary() *Vocabulary {
+	vocabulary, err := readVocabulary(strings.NewReader(bundledVocabulary),
+		"vocabulary.dat")
+	if err != nil {
+		panic(err) // the bundled vocabulary is known to be valid
+	}
+	return vocabulary
+}
+
+// ReadVocabulary reads a debtags vocabulary file, e.g.,
+// /usr/share/debtags/vocabulary.
+func ReadVocabulary(filename string) (*Vocabulary, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", Err109, err)
+	}
+	defer file.Close()
+	return readVocabulary(file, filename)
+}
+
+func readVocabulary(reader io.Reader, label string) (*Vocabulary, error) {
+	vocabulary := newVocabulary()
+	stanzaReader := deb822.NewReaderLabel(reader, label)
+	for {
+		stanza, err := stanzaReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", Err109, err)
+		}
+		vocabulary.add(&stanza)
+	}
+	for _, facet := range vocabulary.Facets {
+		slices.Sort(facet.Tags)
+	}
+	return vocabulary, nil
+}
+
+// add adds a Facet or Tag stanza; tags whose facet hasn't been seen get
+// a facet without descriptions.
+func (me *Vocabulary) add(stanza *deb822.Stanza) {
+	shortDesc, longDesc := splitDescription(stanza.Get("Description"))
+	if name, ok := stanza.Lookup("Facet"); ok {
+		facet := me.facet(name)
+		facet.ShortDesc = shortDesc
+		facet.LongDesc = longDesc
+	} else if name, ok := stanza.Lookup("Tag"); ok {
+		name = strings.ReplaceAll(name, "::", "/")
+		facetName, _ := SplitTag(name)
+		me.Tags[name] = &TagInfo{Name: name, Facet: facetName,
+			ShortDesc: shortDesc, LongDesc: longDesc}
+		facet := me.facet(facetName)
+		facet.Tags = append(facet.Tags, name)
+	}
+}
+
+func (me *Vocabulary) facet(name string) *Facet {
+	facet, ok := me.Facets[name]
+	if !ok {
+		facet = &Facet{Name: name}
+		me.Facets[name] = facet
+	}
+	return facet
+}
+
+// Describe returns the short description of the given tag, e.g.,
+// "use/viewing", or facet, e.g., "use" or "use/*", or "" if it isn't in
+// the vocabulary.
+func (me *Vocabulary) Describe(name string) string {
+	if facet, ok := IsFacetQuery(name); ok {
+		name = facet
+	}
+	if tag, ok := me.Tags[name]; ok {
+		return tag.ShortDesc
+	}
+	if facet, ok := me.Facets[name]; ok {
+		return facet.ShortDesc
+	}
+	return ""
+}
+
+// SplitTag returns the facet and name of a tag, e.g., "use" and "viewing"
+// for "use/viewing"; a tag without a facet has facet "".
+func SplitTag(tag string) (string, string) {
+	facet, name, found := strings.Cut(tag, "/")
+	if !found {
+		return "", tag
+	}
+	return facet, name
+}
+
+// IsFacetQuery returns the facet name and true if the tag is a whole
+// facet, e.g., "implemented-in/*"; otherwise it returns "" and false.
+func IsFacetQuery(tag string) (string, bool) {
+	if facet, ok := strings.CutSuffix(tag, "/*"); ok {
+		return facet, true
+	}
+	return "", false
+}
+
+// TagsByFacet returns the given tags (full names) grouped by facet with
+// each facet's tags in order.
+func TagsByFacet(tags []string) map[string][]string {
+	tagsForFacet := map[string][]string{}
+	for _, tag := range tags {
+		facet, _ := SplitTag(tag)
+		tagsForFacet[facet] = append(tagsForFacet[facet], tag)
+	}
+	for _, tags := range tagsForFacet {
+		slices.Sort(tags)
+	}
+	return tagsForFacet
+}